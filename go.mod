@@ -3,7 +3,11 @@ module github.com/grainme/gator
 go 1.24.5
 
 require (
-	github.com/google/uuid v1.6.0 // indirect
-	github.com/lib/pq v1.10.9 // indirect
-	go.uber.org/atomic v1.11.0 // indirect
+	github.com/andybalholm/brotli v1.2.0
+	github.com/google/uuid v1.6.0
+	github.com/lib/pq v1.10.9
+	golang.org/x/net v0.47.0
+	golang.org/x/text v0.31.0
 )
+
+require go.uber.org/atomic v1.11.0 // indirect
@@ -2,9 +2,14 @@ package cli
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"flag"
 	"fmt"
 	"log/slog"
+	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,70 +18,435 @@ import (
 	"github.com/lib/pq"
 )
 
+const (
+	DefaultAggWorkers = 4
+	DefaultAggPerHost = 2
+	DefaultAggBatch   = 50
+	DefaultAggLease   = 5 * time.Minute
+	DefaultAggRetries = 2
+	// failures in a row after which a feed is paused
+	DefaultAggMaxFailures = 10
+)
+
+type aggOptions struct {
+	interval time.Duration
+	// bounds of the per-feed interval, see nextFetchAt
+	minInterval time.Duration
+	maxInterval time.Duration
+	workers     int
+	perHost     int
+	batch       int
+	// how long a claimed feed stays ours, past that another instance may take it
+	lease time.Duration
+	// identifies this process in feeds.claimed_by
+	instanceID  string
+	retries     int
+	maxFailures int
+	client      *rss.Client
+}
+
 func HandlerAggregator(s *State, cmd Command) error {
-	if len(cmd.Args) < 1 {
-		return fmt.Errorf("usage: agg <time_between_reqs>")
+	usage := fmt.Errorf("usage: agg <time_between_reqs> [--min-interval duration] [--max-interval duration] [--workers n] [--per-host n] [--batch n] [--lease duration] [--retries n] [--max-failures n] [--timeout duration] [--max-body bytes]")
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	minInterval := fs.Duration("min-interval", 0, "shortest time between two fetches of a feed (default time_between_reqs)")
+	maxInterval := fs.Duration("max-interval", DefaultMaxFetchInterval, "longest time between two fetches of a feed")
+	workers := fs.Int("workers", DefaultAggWorkers, "number of feeds fetched in parallel")
+	perHost := fs.Int("per-host", DefaultAggPerHost, "max concurrent fetches against the same host")
+	batch := fs.Int("batch", DefaultAggBatch, "max feeds claimed per cycle")
+	lease := fs.Duration("lease", DefaultAggLease, "how long a claimed feed is reserved for this instance, and the longest a fetch may take")
+	retries := fs.Int("retries", DefaultAggRetries, "how many times a temporary fetch failure is retried")
+	timeout := fs.Duration("timeout", rss.DefaultTimeout, "give up on a feed request after this long")
+	maxBody := fs.Int64("max-body", rss.DefaultMaxBodySize, "largest feed accepted, in bytes")
+	maxFailures := fs.Int("max-failures", DefaultAggMaxFailures, "pause a feed after this many failed fetches in a row, 0 never pauses")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return usage
+	}
+	if len(args) != 1 || *workers < 1 || *perHost < 1 || *batch < 1 || *lease <= 0 || *minInterval < 0 || *maxInterval <= 0 || *retries < 0 || *maxFailures < 0 || *timeout <= 0 || *maxBody < 1 {
+		return usage
 	}
 
-	timeBetweenReqs, err := time.ParseDuration(cmd.Args[0])
+	timeBetweenReqs, err := time.ParseDuration(args[0])
 	if err != nil {
 		return fmt.Errorf("invalid duration format: %w", err)
 	}
-	slog.Info("starting feed collection", "interval", timeBetweenReqs)
+	if timeBetweenReqs <= 0 {
+		return usage
+	}
+	if *minInterval == 0 {
+		*minInterval = timeBetweenReqs
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	opts := aggOptions{
+		interval:    timeBetweenReqs,
+		minInterval: *minInterval,
+		maxInterval: *maxInterval,
+		workers:     *workers,
+		perHost:     *perHost,
+		batch:       *batch,
+		lease:       *lease,
+		instanceID:  fmt.Sprintf("%s-%d-%s", hostname, os.Getpid(), uuid.NewString()[:8]),
+		retries:     *retries,
+		maxFailures: *maxFailures,
+		client:      rss.NewClient(*timeout, *maxBody),
+	}
+	slog.Info("starting feed collection", "interval", opts.interval, "workers", opts.workers, "per_host", opts.perHost, "instance", opts.instanceID)
 
+	limiter := newHostLimiter(opts.perHost)
 	ticker := time.NewTicker(timeBetweenReqs)
 	for ; ; <-ticker.C {
-		err := ScrapeFeeds(s)
+		// failures are recorded against their feed, one of them (or the
+		// database going away for a while) shouldn't stop the aggregator
+		err := scrapeFeeds(s, opts, limiter)
 		if err != nil {
-			return err
+			slog.Error("feed collection failed", "err", err)
 		}
 	}
 }
 
-func ScrapeFeeds(s *State) error {
-	feed, err := s.Db.GetNextFeedToFetch(context.Background())
+// scrapeFeeds claims the feeds that are due (see nextFetchAt) and fetches
+// them on a pool of workers. claimed feeds are leased to this instance, so
+// other aggregators running against the same database skip them. the lease
+// is renewed right before each fetch, it only has to cover one feed.
+func scrapeFeeds(s *State, opts aggOptions, limiter *hostLimiter) error {
+	feeds, err := s.Db.ClaimFeedsToFetch(context.Background(), database.ClaimFeedsToFetchParams{
+		ClaimedBy:    opts.instanceID,
+		LeaseSeconds: opts.lease.Seconds(),
+		BatchSize:    int32(opts.batch),
+	})
 	if err != nil {
 		return err
 	}
+	if len(feeds) == 0 {
+		return nil
+	}
+	slog.Info("claimed feeds to fetch", "count", len(feeds))
+
+	jobs := make(chan database.Feed)
+	errs := make(chan error, 2*len(feeds))
+	var wg sync.WaitGroup
+	for range min(opts.workers, len(feeds)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for feed := range jobs {
+				release := limiter.acquire(feedHost(feed.Url))
+				// the feed may have waited in the queue past its lease, renew
+				// it now and leave the feed alone if another instance took it
+				renewed, err := s.Db.RenewFeedLease(context.Background(), database.RenewFeedLeaseParams{
+					LeaseSeconds: opts.lease.Seconds(),
+					ID:           feed.ID,
+					ClaimedBy:    opts.instanceID,
+				})
+				if err != nil {
+					release()
+					errs <- err
+					continue
+				}
+				if renewed == 0 {
+					release()
+					slog.Info("feed claimed by another instance, skipping", "name", feed.Name, "url", feed.Url)
+					continue
+				}
+
+				nextFetch, fetchErr, err := scrapeFeed(s, feed, opts)
+				release()
+				if err != nil {
+					// not the feed's fault, try again soon without touching its health
+					errs <- fmt.Errorf("failed to save feed %q: %w", feed.Url, err)
+					nextFetch = time.Now().Add(opts.minInterval)
+				} else if fetchErr != nil {
+					slog.Error("failed to scrape feed", "name", feed.Name, "url", feed.Url, "err", fetchErr)
+					nextFetch, err = recordFeedFailure(s, feed, fetchErr, opts)
+					if err != nil {
+						errs <- err
+					}
+				}
+
+				err = s.Db.MarkFeedFetched(context.Background(), database.MarkFeedFetchedParams{
+					NextFetchIn: time.Until(nextFetch).Seconds(),
+					ID:          feed.ID,
+					ClaimedBy:   opts.instanceID,
+				})
+				if err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
 
-	slog.Info("found feed to fetch", "name", feed.Name, "url", feed.Url)
-	err = s.Db.MarkFeedFetched(context.Background(), feed.ID)
+	for _, feed := range interleaveByHost(feeds) {
+		jobs <- feed
+	}
+	close(jobs)
+	wg.Wait()
+	close(errs)
+
+	var scrapeErrs []error
+	for err := range errs {
+		scrapeErrs = append(scrapeErrs, err)
+	}
+	return errors.Join(scrapeErrs...)
+}
+
+// scrapeFeed fetches feed and saves its items, it returns when the feed
+// should be fetched next. fetchErr is the feed failing us and counts against
+// its health, err is ours (e.g. the database) and doesn't.
+func scrapeFeed(s *State, feed database.Feed, opts aggOptions) (nextFetch time.Time, fetchErr, err error) {
+	slog.Info("fetching feed", "name", feed.Name, "url", feed.Url)
+	// retries may add up to more than the lease, don't keep fetching a feed
+	// another instance is free to claim
+	ctx, cancel := context.WithTimeout(context.Background(), opts.lease)
+	defer cancel()
+	result, err := opts.client.FetchFeed(ctx, feed.Url, rss.FetchOptions{
+		ETag:         feed.Etag.String,
+		LastModified: feed.LastModified.String,
+		Retries:      opts.retries,
+	})
 	if err != nil {
-		return nil
+		return time.Time{}, fmt.Errorf("failed to fetch feed from %q: %w", feed.Url, err), nil
+	}
+	if result.PermanentURL != "" && result.PermanentURL != feed.Url {
+		feed = moveFeed(s, feed, result.PermanentURL)
 	}
 
-	feedItems, err := rss.FetchFeed(context.Background(), feed.Url)
+	err = s.Db.RecordFeedSuccess(context.Background(), database.RecordFeedSuccessParams{
+		ID:             feed.ID,
+		LastStatusCode: sql.NullInt32{Int32: int32(result.StatusCode), Valid: true},
+	})
 	if err != nil {
-		return fmt.Errorf("failed to fetch feed from %q: %w", feed.Url, err)
+		return time.Time{}, nil, err
 	}
 
-	// convert pubDate (string) to time
-	// this format: Mon, 01 Jan 0001 00:00:00 +0000
-	for _, item := range feedItems.Channel.Item {
-		publishedAt, err := time.Parse(time.RFC1123Z, item.PubDate)
-		if err != nil {
-			return err
-		}
-		post := database.CreatePostParams{
-			ID:          uuid.New(),
-			CreatedAt:   time.Now(),
-			UpdatedAt:   time.Now(),
-			Title:       item.Title,
-			Description: item.Description,
-			PublishedAt: publishedAt,
-			FeedID:      feed.ID,
+	if result.NotModified {
+		slog.Info("feed not modified", "name", feed.Name, "url", feed.Url)
+		return keepInterval(time.Now(), feed, opts.minInterval, opts.maxInterval), nil, nil
+	}
+	if err := saveFetchedFeed(s, feed, result); err != nil {
+		return time.Time{}, nil, err
+	}
+	return nextFetchAt(time.Now(), result.Feed, opts.minInterval, opts.maxInterval), nil, nil
+}
+
+// saveFetchedFeed stores a feed document we just downloaded: what the feed
+// says about itself, its items and the validators for the next request.
+func saveFetchedFeed(s *State, feed database.Feed, result *rss.FetchResult) error {
+	if err := updateFeedMetadata(s, feed, result.Feed); err != nil {
+		return err
+	}
+
+	// validators are only kept once every item is stored, a 304 on the next
+	// fetch would otherwise hide the items we failed to save for good
+	if failed := saveFeedItems(s, feed, result.Feed); failed > 0 {
+		return nil
+	}
+	return s.Db.SetFeedCacheValidators(context.Background(), database.SetFeedCacheValidatorsParams{
+		ID:           feed.ID,
+		Etag:         sql.NullString{String: result.ETag, Valid: result.ETag != ""},
+		LastModified: sql.NullString{String: result.LastModified, Valid: result.LastModified != ""},
+	})
+}
+
+// saveFeedItems stores the items of a freshly fetched feed and returns how
+// many of them could not be saved.
+func saveFeedItems(s *State, feed database.Feed, rssFeed *rss.RSSFeed) (failed int) {
+	// items without a usable date are kept and dated when we first saw them,
+	// one bad pubDate shouldn't cost us the rest of the feed.
+	firstSeen := time.Now()
+	// item links may be relative to the site, which may itself be relative to the feed
+	siteURL := rss.ResolveURL(feed.Url, rssFeed.Channel.Link)
+	for _, item := range rssFeed.Channel.Item {
+		postURL := item.URL(siteURL)
+		if postURL == "" {
+			// Atom entries and JSON Feed items may only have an id, they
+			// still are posts: keep them and point them at the site
+			if strings.TrimSpace(item.GUID.Value) == "" && strings.TrimSpace(item.Title) == "" {
+				slog.Warn("item has no link, guid or title, skipping", "feed", feed.Url)
+				continue
+			}
+			postURL = siteURL
 		}
 
-		_, err = s.Db.CreatePost(context.Background(), post)
+		publishedAt, err := item.Published()
 		if err != nil {
-			// unique_violation (e.g: duplicate)
-			var pqErr *pq.Error
-			if errors.As(err, &pqErr) && pqErr.Code == "23505" {
-				slog.Info("post already exists, skipping", "url", item.Link)
-				continue
+			if !errors.Is(err, rss.ErrNoDate) {
+				slog.Warn("could not parse item date, using first-seen time", "feed", feed.Url, "title", item.Title, "err", err)
 			}
+			publishedAt = firstSeen
+		}
+
+		if err := savePost(s, feed, item, postURL, publishedAt); err != nil {
+			slog.Error("failed to save post", "url", postURL, "err", err)
+			failed++
 		}
 	}
+	return failed
+}
+
+// updateFeedMetadata stores what the feed says about itself, it may change
+// over time so it is refreshed on every full fetch.
+func updateFeedMetadata(s *State, feed database.Feed, rssFeed *rss.RSSFeed) error {
+	siteURL := strings.TrimSpace(rssFeed.Channel.Link)
+	if siteURL != "" {
+		siteURL = rss.ResolveURL(feed.Url, siteURL)
+	}
+	iconURL := rssFeed.IconURL()
+	if iconURL != "" {
+		iconURL = rss.ResolveURL(feed.Url, iconURL)
+	}
+	description := strings.TrimSpace(rssFeed.Channel.Description)
+	language := strings.TrimSpace(rssFeed.Channel.Language)
+
+	return s.Db.UpdateFeedMetadata(context.Background(), database.UpdateFeedMetadataParams{
+		ID:          feed.ID,
+		SiteUrl:     sql.NullString{String: siteURL, Valid: siteURL != ""},
+		Description: sql.NullString{String: description, Valid: description != ""},
+		IconUrl:     sql.NullString{String: iconURL, Valid: iconURL != ""},
+		Language:    sql.NullString{String: language, Valid: language != ""},
+	})
+}
+
+// moveFeed points feed at the url it was permanently redirected to. the old
+// url stays an alias, so following it keeps working.
+func moveFeed(s *State, feed database.Feed, newURL string) database.Feed {
+	moved, err := s.Db.MoveFeedUrl(context.Background(), database.MoveFeedUrlParams{
+		ID:        feed.ID,
+		NewUrl:    newURL,
+		HistoryID: uuid.New(),
+	})
+	if err != nil {
+		// most likely someone added the new url as a feed of its own
+		slog.Warn("could not update moved feed url", "name", feed.Name, "url", feed.Url, "new_url", newURL, "err", err)
+		return feed
+	}
+
+	slog.Info("feed moved permanently", "name", feed.Name, "old_url", feed.Url, "url", moved.Url)
+	return moved
+}
+
+// recordFeedFailure updates the feed's health after a failed fetch, pausing
+// it past opts.maxFailures, and returns when to try again.
+func recordFeedFailure(s *State, feed database.Feed, fetchErr error, opts aggOptions) (time.Time, error) {
+	var statusCode sql.NullInt32
+	var rssErr *rss.FetchError
+	if errors.As(fetchErr, &rssErr) && rssErr.StatusCode != 0 {
+		statusCode = sql.NullInt32{Int32: int32(rssErr.StatusCode), Valid: true}
+	}
+
+	updated, err := s.Db.RecordFeedError(context.Background(), database.RecordFeedErrorParams{
+		LastError:      sql.NullString{String: fetchErr.Error(), Valid: true},
+		LastStatusCode: statusCode,
+		MaxFailures:    int32(opts.maxFailures),
+		ID:             feed.ID,
+	})
+	if err != nil {
+		return time.Now().Add(opts.minInterval), err
+	}
+
+	if updated.PausedAt.Valid && !feed.PausedAt.Valid {
+		slog.Warn("feed paused after too many failures", "name", feed.Name, "url", feed.Url, "failures", updated.ConsecutiveFailures)
+	}
+	return retryFetchAt(time.Now(), fetchErr, updated.ConsecutiveFailures, opts.minInterval, opts.maxInterval), nil
+}
+
+// savePost stores a new item, or updates the stored post (keeping the old
+// version as a revision) when the author edited it since we last saw it.
+func savePost(s *State, feed database.Feed, item rss.RSSItem, postURL string, publishedAt time.Time) error {
+	guid := item.ID()
+	contentHash := item.ContentHash()
+	// the timestamp columns have no time zone and postgres drops the offset
+	// we send, keep them all in UTC so posts from different zones compare
+	publishedAt = publishedAt.UTC()
+	sourceUpdatedAt := itemUpdatedAt(item)
+
+	existing, err := s.Db.GetPostByFeedAndGuid(context.Background(), database.GetPostByFeedAndGuidParams{
+		FeedID: feed.ID,
+		Guid:   guid,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		// pruned posts are gone for good, even when the feed still lists them
+		pruned, err := s.Db.IsPostPruned(context.Background(), database.IsPostPrunedParams{
+			FeedID: feed.ID,
+			Guid:   guid,
+		})
+		if err != nil || pruned {
+			return err
+		}
 
+		_, err = s.Db.CreatePost(context.Background(), database.CreatePostParams{
+			ID:              uuid.New(),
+			CreatedAt:       time.Now().UTC(),
+			UpdatedAt:       time.Now().UTC(),
+			Title:           item.Title,
+			Url:             postURL,
+			Description:     item.Description,
+			PublishedAt:     publishedAt,
+			FeedID:          feed.ID,
+			Guid:            guid,
+			ContentHash:     contentHash,
+			SourceUpdatedAt: sourceUpdatedAt,
+		})
+		// unique_violation on (feed_id, guid), someone stored it in the meantime
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			slog.Info("post already exists, skipping", "url", postURL, "guid", guid)
+			return nil
+		}
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if !postEdited(existing, item, contentHash, sourceUpdatedAt) {
+		return nil
+	}
+
+	_, err = s.Db.UpdatePostContent(context.Background(), database.UpdatePostContentParams{
+		RevisionID:      uuid.New(),
+		PostID:          existing.ID,
+		Title:           item.Title,
+		Url:             postURL,
+		Description:     item.Description,
+		ContentHash:     contentHash,
+		SourceUpdatedAt: sourceUpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("post updated", "id", existing.ID, "title", item.Title, "url", postURL)
 	return nil
 }
+
+// itemUpdatedAt is the date the item says it was last edited, in UTC like
+// every date we store.
+func itemUpdatedAt(item rss.RSSItem) sql.NullTime {
+	updatedAt, err := item.LastUpdated()
+	if err != nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: updatedAt.UTC(), Valid: true}
+}
+
+// postEdited tells whether item is a new version of the stored post.
+func postEdited(existing database.GetPostByFeedAndGuidRow, item rss.RSSItem, contentHash string, sourceUpdatedAt sql.NullTime) bool {
+	if existing.ContentHash == contentHash {
+		return false
+	}
+	// posts stored before we hashed content have an empty hash
+	if existing.ContentHash == "" && existing.Title == item.Title && existing.Description == item.Description {
+		return false
+	}
+	// when the feed dates its edits, a date that didn't move means the
+	// difference is noise (tracking parameters, reordered markup...)
+	if sourceUpdatedAt.Valid && existing.SourceUpdatedAt.Valid && !sourceUpdatedAt.Time.After(existing.SourceUpdatedAt.Time) {
+		return false
+	}
+	return true
+}
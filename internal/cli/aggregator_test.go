@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/grainme/gator/internal/database"
+	"github.com/grainme/gator/internal/rss"
+)
+
+// stored reads t back the way a TIMESTAMP column returns it: same clock
+// reading, zone dropped and reported as UTC.
+func stored(t sql.NullTime) sql.NullTime {
+	wall := t.Time
+	t.Time = time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), time.UTC)
+	return t
+}
+
+func TestPostEdited(t *testing.T) {
+	original := rss.RSSItem{Title: "Post", Link: "https://example.com/post", Description: "v1", Updated: "2024-05-01T10:00:00+02:00"}
+	existing := database.GetPostByFeedAndGuidRow{
+		Title:           original.Title,
+		Description:     original.Description,
+		ContentHash:     original.ContentHash(),
+		SourceUpdatedAt: stored(itemUpdatedAt(original)),
+	}
+
+	tests := []struct {
+		name   string
+		item   rss.RSSItem
+		edited bool
+	}{
+		{name: "unchanged", item: original, edited: false},
+		{name: "edited an hour later in a +02:00 feed", item: rss.RSSItem{Title: "Post", Link: original.Link, Description: "v2", Updated: "2024-05-01T11:00:00+02:00"}, edited: true},
+		{name: "edited, same date in another zone", item: rss.RSSItem{Title: "Post", Link: original.Link, Description: "v2", Updated: "2024-05-01T08:00:00Z"}, edited: false},
+		{name: "edited, date in the past", item: rss.RSSItem{Title: "Post", Link: original.Link, Description: "v2", Updated: "2024-05-01T09:00:00+02:00"}, edited: false},
+		{name: "edited, undated", item: rss.RSSItem{Title: "Post", Link: original.Link, Description: "v2"}, edited: true},
+	}
+	for _, tt := range tests {
+		got := postEdited(existing, tt.item, tt.item.ContentHash(), itemUpdatedAt(tt.item))
+		if got != tt.edited {
+			t.Errorf("%s: postEdited = %v, want %v", tt.name, got, tt.edited)
+		}
+	}
+
+	legacy := database.GetPostByFeedAndGuidRow{Title: "Post", Description: "v1"}
+	if postEdited(legacy, original, original.ContentHash(), sql.NullTime{}) {
+		t.Error("post stored without hash reported as edited")
+	}
+}
@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/grainme/gator/internal/rss"
+)
+
+// fetchOrDiscoverFeed fetches rawURL, and when it turns out to be a web page
+// rather than a feed, looks for the feeds the site offers and asks the user
+// which one they meant. it returns the fetched feed and the url to store it
+// under.
+func fetchOrDiscoverFeed(ctx context.Context, rawURL string) (string, *rss.FetchResult, error) {
+	feedURL := rawURL
+	result, err := rss.FetchFeed(ctx, feedURL, rss.FetchOptions{})
+	var notFeed *rss.NotFeedError
+	if errors.As(err, &notFeed) {
+		links := rss.DiscoverFeeds(ctx, notFeed.Body, notFeed.ContentType, notFeed.URL)
+		if len(links) == 0 {
+			return "", nil, fmt.Errorf("%s is a web page and no feed was found on it", rawURL)
+		}
+		link, chooseErr := chooseFeedLink(os.Stdin, os.Stdout, links)
+		if chooseErr != nil {
+			return "", nil, chooseErr
+		}
+		slog.Info("feed discovered", "page", rawURL, "url", link.URL)
+
+		feedURL = link.URL
+		result, err = rss.FetchFeed(ctx, feedURL, rss.FetchOptions{})
+	}
+	if err != nil {
+		return "", nil, invalidFeedError(feedURL, err)
+	}
+	return cmp.Or(result.PermanentURL, feedURL), result, nil
+}
+
+// invalidFeedError explains why feedURL can't be added.
+func invalidFeedError(feedURL string, err error) error {
+	var fetchErr *rss.FetchError
+	if errors.As(err, &fetchErr) && fetchErr.Kind == rss.ErrorParse {
+		return fmt.Errorf("%s is not a valid RSS, Atom or JSON feed: %w", feedURL, fetchErr.Err)
+	}
+	return fmt.Errorf("could not fetch %s: %w", feedURL, err)
+}
+
+// chooseFeedLink prompts for one of links, unless there is only one.
+func chooseFeedLink(in io.Reader, out io.Writer, links []rss.FeedLink) (rss.FeedLink, error) {
+	if len(links) == 1 {
+		return links[0], nil
+	}
+
+	fmt.Fprintln(out, "this page offers several feeds:")
+	for i, link := range links {
+		fmt.Fprintf(out, "  %d) %s  %s\n", i+1, cmp.Or(link.Title, "untitled"), link.URL)
+	}
+	fmt.Fprintf(out, "pick one [1-%d]: ", len(links))
+
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return rss.FeedLink{}, fmt.Errorf("no feed picked: %w", err)
+	}
+	choice, err := strconv.Atoi(strings.TrimSpace(line))
+	if err != nil || choice < 1 || choice > len(links) {
+		return rss.FeedLink{}, fmt.Errorf("invalid choice %q", strings.TrimSpace(line))
+	}
+	return links[choice-1], nil
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/grainme/gator/internal/rss"
+)
+
+func TestChooseFeedLink(t *testing.T) {
+	links := []rss.FeedLink{
+		{URL: "https://example.com/feed", Title: "Posts"},
+		{URL: "https://example.com/comments/feed"},
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		links   []rss.FeedLink
+		want    string
+		wantErr bool
+	}{
+		{name: "only one, no prompt", links: links[:1], want: "https://example.com/feed"},
+		{name: "first", input: "1\n", links: links, want: "https://example.com/feed"},
+		{name: "second, spaces, no newline", input: " 2 ", links: links, want: "https://example.com/comments/feed"},
+		{name: "out of range", input: "3\n", links: links, wantErr: true},
+		{name: "zero", input: "0\n", links: links, wantErr: true},
+		{name: "not a number", input: "posts\n", links: links, wantErr: true},
+		{name: "no input", input: "", links: links, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			got, err := chooseFeedLink(strings.NewReader(tt.input), &out, tt.links)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("chooseFeedLink = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil || got.URL != tt.want {
+				t.Errorf("chooseFeedLink = %+v, %v, want %s", got, err, tt.want)
+			}
+			if len(tt.links) == 1 && out.Len() > 0 {
+				t.Errorf("prompted for a single feed: %q", out.String())
+			}
+		})
+	}
+
+	var out bytes.Buffer
+	chooseFeedLink(strings.NewReader("1\n"), &out, links)
+	for _, want := range []string{"1) Posts  https://example.com/feed", "2) untitled  https://example.com/comments/feed", "[1-2]"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("prompt %q doesn't contain %q", out.String(), want)
+		}
+	}
+}
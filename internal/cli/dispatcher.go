@@ -1,6 +1,7 @@
 package cli
 
 import (
+	"flag"
 	"fmt"
 
 	"github.com/grainme/gator/internal/config"
@@ -37,3 +38,20 @@ func (c *Commands) Register(cmd string, f func(*State, Command) error) error {
 	c.RegistredCommands[cmd] = f
 	return nil
 }
+
+// parseFlags parses the flags in args and returns the positional arguments,
+// unlike fs.Parse it doesn't stop at the first positional argument.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -2,8 +2,11 @@ package cli
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -32,12 +35,29 @@ func HandlerGetFeeds(s *State, cmd Command) error {
 }
 
 func HandlerAddFeed(s *State, cmd Command, currentUser database.User) error {
-	if len(cmd.Args) < 2 {
-		return fmt.Errorf("usage: addfeed <name> <url>")
+	if len(cmd.Args) < 1 || len(cmd.Args) > 2 {
+		return fmt.Errorf("usage: addfeed [name] <url>")
+	}
+
+	rawURL := cmd.Args[len(cmd.Args)-1]
+	// people often paste the address of the website, not of its feed
+	feedURL, result, err := fetchOrDiscoverFeed(context.Background(), rawURL)
+	if err != nil {
+		return err
+	}
+
+	feedName := strings.TrimSpace(result.Feed.Channel.Title)
+	if len(cmd.Args) == 2 {
+		feedName = cmd.Args[0]
+	}
+	if feedName == "" {
+		return fmt.Errorf("%s has no title, give it a name: addfeed <name> <url>", feedURL)
+	}
+
+	if existing, err := s.Db.GetFeedByUrl(context.Background(), feedURL); err == nil {
+		return fmt.Errorf("feed %q already exists at %s, follow it instead", existing.Name, existing.Url)
 	}
 
-	feedName := cmd.Args[0]
-	feedURL := cmd.Args[1]
 	feedParams := database.CreateFeedParams{
 		ID:        uuid.New(),
 		CreatedAt: time.Now(),
@@ -64,7 +84,28 @@ func HandlerAddFeed(s *State, cmd Command, currentUser database.User) error {
 		return err
 	}
 
-	slog.Info("feed created", "name", feedCreated.Name, "url", feedCreated.Url, "id", feedCreated.ID)
+	// we have the feed already, no need to wait for agg to see its posts
+	err = s.Db.RecordFeedSuccess(context.Background(), database.RecordFeedSuccessParams{
+		ID:             feedCreated.ID,
+		LastStatusCode: sql.NullInt32{Int32: int32(result.StatusCode), Valid: true},
+	})
+	if err != nil {
+		return err
+	}
+	if err := saveFetchedFeed(s, feedCreated, result); err != nil {
+		return err
+	}
+	// agg would otherwise fetch it again first thing, it never was
+	nextFetch := nextFetchAt(time.Now(), result.Feed, DefaultMinFetchInterval, DefaultMaxFetchInterval)
+	err = s.Db.ScheduleFeed(context.Background(), database.ScheduleFeedParams{
+		NextFetchIn: time.Until(nextFetch).Seconds(),
+		ID:          feedCreated.ID,
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("feed created", "name", feedCreated.Name, "url", feedCreated.Url, "id", feedCreated.ID, "posts", len(result.Feed.Channel.Item))
 	return nil
 }
 
@@ -73,8 +114,7 @@ func HandlerFollow(s *State, cmd Command, currentUser database.User) error {
 		return fmt.Errorf("usage: follow <url>")
 	}
 
-	feedURL := cmd.Args[0]
-	feed, err := s.Db.GetFeedByUrl(context.Background(), feedURL)
+	feed, err := findFeed(s, cmd.Args[0])
 	if err != nil {
 		return err
 	}
@@ -95,22 +135,38 @@ func HandlerFollow(s *State, cmd Command, currentUser database.User) error {
 	return nil
 }
 
+// findFeed looks rawURL up, and when no feed has that url, looks for the
+// feed of the website it points to.
+func findFeed(s *State, rawURL string) (database.Feed, error) {
+	feed, err := s.Db.GetFeedByUrl(context.Background(), rawURL)
+	if !errors.Is(err, sql.ErrNoRows) {
+		return feed, err
+	}
+
+	feedURL, _, err := fetchOrDiscoverFeed(context.Background(), rawURL)
+	if err != nil {
+		return database.Feed{}, fmt.Errorf("no feed with url %q: %w", rawURL, err)
+	}
+
+	feed, err = s.Db.GetFeedByUrl(context.Background(), feedURL)
+	if errors.Is(err, sql.ErrNoRows) {
+		return database.Feed{}, fmt.Errorf("no feed with url %q, add it with addfeed", feedURL)
+	}
+	return feed, err
+}
+
 func HandlerFollowing(s *State, cmd Command, currentUser database.User) error {
 	if len(cmd.Args) > 0 {
 		return fmt.Errorf("usage: following")
 	}
 
-	feedFollows, err := s.Db.GetFeedFollowsForUser(context.Background(), currentUser.ID)
+	feeds, err := s.Db.GetUnreadCountsForUser(context.Background(), currentUser.ID)
 	if err != nil {
 		return err
 	}
 
-	for _, feedFollow := range feedFollows {
-		feedFollowExtraInfos, err := s.Db.GetFeedFollowByFeedId(context.Background(), feedFollow.FeedID)
-		if err != nil {
-			return err
-		}
-		slog.Info("following", "feed", feedFollowExtraInfos.Feedname)
+	for _, feed := range feeds {
+		slog.Info("following", "feed", feed.Name, "url", feed.Url, "category", feed.Category.String, "unread", feed.Unread)
 	}
 
 	return nil
@@ -138,3 +194,84 @@ func HandlerUnfollow(s *State, cmd Command, currentUser database.User) error {
 
 	return nil
 }
+
+// HandlerFeedStatus lists the feeds whose last fetches failed, paused ones
+// first, so broken subscriptions can be fixed or removed.
+func HandlerFeedStatus(s *State, cmd Command) error {
+	if len(cmd.Args) > 0 {
+		return fmt.Errorf("usage: feedstatus")
+	}
+
+	feeds, err := s.Db.GetBrokenFeeds(context.Background())
+	if err != nil {
+		return err
+	}
+
+	if len(feeds) == 0 {
+		slog.Info("all feeds are healthy")
+		return nil
+	}
+	for _, feed := range feeds {
+		slog.Info("feed",
+			"name", feed.Name,
+			"url", feed.Url,
+			"paused", feed.PausedAt.Valid,
+			"failures", feed.ConsecutiveFailures,
+			"last_status", feed.LastStatusCode.Int32,
+			"last_error", feed.LastError.String,
+			"last_error_at", formatNullTime(feed.LastErrorAt),
+			"last_success_at", formatNullTime(feed.LastSuccessAt),
+		)
+	}
+	return nil
+}
+
+func HandlerResumeFeed(s *State, cmd Command) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: resumefeed <url>")
+	}
+
+	feed, err := s.Db.GetFeedByUrl(context.Background(), cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("no feed with url %q: %w", cmd.Args[0], err)
+	}
+
+	if _, err := s.Db.ResumeFeed(context.Background(), feed.Url); err != nil {
+		return err
+	}
+
+	slog.Info("feed resumed", "name", feed.Name, "url", feed.Url)
+	return nil
+}
+
+func HandlerFeedHistory(s *State, cmd Command) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: feedhistory <url>")
+	}
+
+	feed, err := s.Db.GetFeedByUrl(context.Background(), cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("no feed with url %q: %w", cmd.Args[0], err)
+	}
+
+	events, err := s.Db.GetFeedHistory(context.Background(), feed.ID)
+	if err != nil {
+		return err
+	}
+
+	if len(events) == 0 {
+		slog.Info("no history for feed", "name", feed.Name, "url", feed.Url)
+		return nil
+	}
+	for _, event := range events {
+		slog.Info("feed event", "at", event.CreatedAt, "event", event.Event, "detail", event.Detail)
+	}
+	return nil
+}
+
+func formatNullTime(t sql.NullTime) string {
+	if !t.Valid {
+		return "never"
+	}
+	return t.Time.Format(time.RFC3339)
+}
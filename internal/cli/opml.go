@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/grainme/gator/internal/database"
+	"github.com/grainme/gator/internal/opml"
+	"github.com/lib/pq"
+)
+
+// HandlerImport follows every feed of an OPML file, adding the ones nobody
+// added yet. the folders of the file become the categories of the follows.
+func HandlerImport(s *State, cmd Command, currentUser database.User) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: import <file.opml>")
+	}
+
+	file, err := os.Open(cmd.Args[0])
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	doc, err := opml.Parse(file)
+	if err != nil {
+		return fmt.Errorf("could not read %s: %w", cmd.Args[0], err)
+	}
+
+	var created, followed, skipped, failed int
+	for _, feed := range doc.Feeds() {
+		isNew, err := importFeed(s, currentUser, feed)
+		switch {
+		case errors.Is(err, errAlreadyFollowing):
+			slog.Info("already following, skipping", "url", feed.URL)
+			skipped++
+		case err != nil:
+			slog.Error("failed to import feed", "title", feed.Title, "url", feed.URL, "err", err)
+			failed++
+		case isNew:
+			slog.Info("feed created", "title", feed.Title, "url", feed.URL, "category", feed.Category)
+			created++
+		default:
+			slog.Info("feed followed", "title", feed.Title, "url", feed.URL, "category", feed.Category)
+			followed++
+		}
+	}
+
+	slog.Info("import done", "created", created, "followed", followed, "skipped", skipped, "failed", failed)
+	return nil
+}
+
+var errAlreadyFollowing = errors.New("already following this feed")
+
+// importFeed follows feed for user, creating it first when it's not known,
+// and reports whether it had to be created. feeds are not fetched here, agg
+// will pick them up on its next cycle.
+func importFeed(s *State, user database.User, feed opml.Feed) (bool, error) {
+	parsed, err := url.Parse(feed.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return false, fmt.Errorf("not an http(s) url: %q", feed.URL)
+	}
+
+	isNew := false
+	existing, err := s.Db.GetFeedByUrl(context.Background(), feed.URL)
+	if errors.Is(err, sql.ErrNoRows) {
+		existing, err = createImportedFeed(s, user, feed)
+		isNew = true
+	}
+	if err != nil {
+		return false, err
+	}
+
+	_, err = s.Db.CreateFeedFollow(context.Background(), database.CreateFeedFollowParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		UserID:    user.ID,
+		FeedID:    existing.ID,
+		Category:  sql.NullString{String: feed.Category, Valid: feed.Category != ""},
+	})
+	// unique_violation on (user_id, feed_id)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return false, errAlreadyFollowing
+	}
+	return isNew, err
+}
+
+func createImportedFeed(s *State, user database.User, feed opml.Feed) (database.Feed, error) {
+	created, err := s.Db.CreateFeed(context.Background(), database.CreateFeedParams{
+		ID:        uuid.New(),
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+		Name:      cmp.Or(feed.Title, feed.URL),
+		Url:       feed.URL,
+		UserID:    user.ID,
+	})
+	if err != nil {
+		return database.Feed{}, err
+	}
+
+	// the first fetch fills in the rest of the metadata
+	if feed.SiteURL != "" {
+		err = s.Db.UpdateFeedMetadata(context.Background(), database.UpdateFeedMetadataParams{
+			ID:      created.ID,
+			SiteUrl: sql.NullString{String: feed.SiteURL, Valid: true},
+		})
+	}
+	return created, err
+}
+
+// HandlerExport writes the feeds the current user follows as an OPML file,
+// or to stdout when no file is given.
+func HandlerExport(s *State, cmd Command, currentUser database.User) error {
+	if len(cmd.Args) < 1 || len(cmd.Args) > 2 || cmd.Args[0] != "opml" {
+		return fmt.Errorf("usage: export opml [file]")
+	}
+
+	follows, err := s.Db.GetFollowedFeedsForUser(context.Background(), currentUser.ID)
+	if err != nil {
+		return err
+	}
+
+	feeds := make([]opml.Feed, 0, len(follows))
+	for _, follow := range follows {
+		feeds = append(feeds, opml.Feed{
+			Title:    follow.Name,
+			URL:      follow.Url,
+			SiteURL:  follow.SiteUrl.String,
+			Category: follow.Category.String,
+		})
+	}
+	doc := opml.New(fmt.Sprintf("%s's subscriptions in gator", currentUser.Name), time.Now(), feeds)
+
+	// logs go to stdout too, so only the document is written there
+	if len(cmd.Args) == 1 {
+		return opml.Write(os.Stdout, doc)
+	}
+
+	file, err := os.Create(cmd.Args[1])
+	if err != nil {
+		return err
+	}
+	if err := opml.Write(file, doc); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	slog.Info("subscriptions exported", "file", cmd.Args[1], "feeds", len(feeds))
+	return nil
+}
@@ -0,0 +1,71 @@
+package cli
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/grainme/gator/internal/database"
+)
+
+// hostLimiter caps how many requests run against the same host at once,
+// whatever the size of the worker pool.
+type hostLimiter struct {
+	mu    sync.Mutex
+	limit int
+	slots map[string]chan struct{}
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{
+		limit: limit,
+		slots: map[string]chan struct{}{},
+	}
+}
+
+// acquire blocks until a slot for host is free, call the returned func to release it.
+func (l *hostLimiter) acquire(host string) func() {
+	l.mu.Lock()
+	slot, ok := l.slots[host]
+	if !ok {
+		slot = make(chan struct{}, l.limit)
+		l.slots[host] = slot
+	}
+	l.mu.Unlock()
+
+	slot <- struct{}{}
+	return func() { <-slot }
+}
+
+func feedHost(feedURL string) string {
+	parsed, err := url.Parse(feedURL)
+	if err != nil || parsed.Host == "" {
+		return feedURL
+	}
+	return strings.ToLower(parsed.Hostname())
+}
+
+// interleaveByHost reorders feeds round-robin across hosts, so workers don't
+// all end up waiting on the same host's limit while other hosts sit idle.
+func interleaveByHost(feeds []database.Feed) []database.Feed {
+	var hosts []string
+	byHost := map[string][]database.Feed{}
+	for _, feed := range feeds {
+		host := feedHost(feed.Url)
+		if _, ok := byHost[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		byHost[host] = append(byHost[host], feed)
+	}
+
+	ordered := make([]database.Feed, 0, len(feeds))
+	for len(ordered) < len(feeds) {
+		for _, host := range hosts {
+			if queue := byHost[host]; len(queue) > 0 {
+				ordered = append(ordered, queue[0])
+				byHost[host] = queue[1:]
+			}
+		}
+	}
+	return ordered
+}
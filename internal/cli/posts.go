@@ -2,31 +2,347 @@ package cli
 
 import (
 	"context"
+	"database/sql"
+	"encoding/base64"
+	"flag"
 	"fmt"
 	"log/slog"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/grainme/gator/internal/database"
+	"github.com/grainme/gator/internal/rss"
 )
 
 const (
 	DefaultPostLimit = 2
+	// starred and saved posts are listed, not browsed, show more of them
+	DefaultListLimit = 20
 )
 
 func HandlerBrowse(s *State, cmd Command, currentUser database.User) error {
-	limit := DefaultPostLimit
+	usage := fmt.Errorf("usage: %s [limit] [--unread=false] [--feed url] [--category name] [--since date] [--until date] [--sort published|fetched] [--offset n | --cursor cursor]", cmd.Name)
 
-	if len(cmd.Args) == 1 {
-		if userLimit, err := strconv.Atoi(cmd.Args[0]); err == nil && userLimit > 0 {
-			limit = userLimit
-		} else {
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	unreadOnly := fs.Bool("unread", true, "only show posts not read yet")
+	feedURL := fs.String("feed", "", "only show the posts of this feed")
+	category := fs.String("category", "", "only show the posts of the feeds followed in this category")
+	since := fs.String("since", "", "only show posts published (or fetched, see --sort) since this date")
+	until := fs.String("until", "", "only show posts published (or fetched, see --sort) before this date")
+	sortBy := fs.String("sort", "published", "order posts by published or fetched date, newest first")
+	offset := fs.Int("offset", 0, "skip this many posts")
+	cursor := fs.String("cursor", "", "continue after the page that handed out this cursor")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil {
+		return usage
+	}
+	limit, ok := parseLimit(args, DefaultPostLimit)
+	if !ok || *offset < 0 || (*offset > 0 && *cursor != "") || (*sortBy != "published" && *sortBy != "fetched") {
+		return usage
+	}
+
+	params := database.BrowsePostsByPublishedParams{
+		UserID:     currentUser.ID,
+		UnreadOnly: *unreadOnly,
+		PostLimit:  int32(limit),
+		PostOffset: int32(*offset),
+	}
+	if *feedURL != "" {
+		feed, err := s.Db.GetFeedByUrl(context.Background(), *feedURL)
+		if err != nil {
+			return fmt.Errorf("no feed with url %q: %w", *feedURL, err)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if name := strings.Trim(*category, "/"); name != "" {
+		params.Category = sql.NullString{String: name, Valid: true}
+	}
+	if params.Since, err = parseNullDate(*since); err != nil {
+		return err
+	}
+	if params.Until, err = parseNullDate(*until); err != nil {
+		return err
+	}
+	if *cursor != "" {
+		after, err := parseBrowseCursor(*cursor)
+		if err != nil {
 			return err
 		}
-	} else if len(cmd.Args) > 1 {
-		return fmt.Errorf("usage: %s <limit>", cmd.Name)
+		if after.Sort != *sortBy {
+			return fmt.Errorf("this cursor is for --sort %s", after.Sort)
+		}
+		params.CursorTime = sql.NullTime{Time: after.Time, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: after.ID, Valid: true}
+	}
+
+	// both queries return the same columns, only the order differs
+	var posts []database.BrowsePostsByPublishedRow
+	if *sortBy == "fetched" {
+		rows, err := s.Db.BrowsePostsByFetched(context.Background(), database.BrowsePostsByFetchedParams(params))
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			posts = append(posts, database.BrowsePostsByPublishedRow(row))
+		}
+	} else {
+		posts, err = s.Db.BrowsePostsByPublished(context.Background(), params)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(posts) == 0 {
+		if *unreadOnly {
+			slog.Info("no unread posts")
+		} else {
+			slog.Info("no posts available")
+		}
+		return nil
+	}
+	for _, post := range posts {
+		slog.Info("post", "id", post.ID, "feed", post.FeedName, "title", post.Title, "url", post.Url, "description", post.Description, "published_at", post.PublishedAt, "read", post.Read)
+	}
+
+	if len(posts) == limit {
+		last := posts[len(posts)-1]
+		next := browseCursor{Sort: *sortBy, Time: last.PublishedAt, ID: last.ID}
+		if *sortBy == "fetched" {
+			next.Time = last.CreatedAt
+		}
+		slog.Info("more posts", "cursor", next.String())
+	}
+	return nil
+}
+
+// browseCursor points after the last post of a page. it is handed out
+// encoded so it can be passed back as is, without caring what's inside.
+type browseCursor struct {
+	Sort string
+	Time time.Time
+	ID   uuid.UUID
+}
+
+func (c browseCursor) String() string {
+	raw := c.Sort + "|" + c.Time.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func parseBrowseCursor(value string) (browseCursor, error) {
+	invalid := fmt.Errorf("invalid cursor %q", value)
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return browseCursor{}, invalid
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return browseCursor{}, invalid
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return browseCursor{}, invalid
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return browseCursor{}, invalid
+	}
+	return browseCursor{Sort: parts[0], Time: t, ID: id}, nil
+}
+
+// parseNullDate parses an optional date flag, in any format feeds use. it
+// is returned in UTC like the post dates it is compared with.
+func parseNullDate(value string) (sql.NullTime, error) {
+	if value == "" {
+		return sql.NullTime{}, nil
+	}
+	date, err := rss.ParseDate(value)
+	if err != nil {
+		return sql.NullTime{}, fmt.Errorf("invalid date %q: %w", value, err)
+	}
+	return sql.NullTime{Time: date.UTC(), Valid: true}, nil
+}
+
+func HandlerRead(s *State, cmd Command, currentUser database.User) error {
+	postID, err := parsePostID(cmd)
+	if err != nil {
+		return err
+	}
+
+	marked, err := s.Db.MarkPostRead(context.Background(), database.MarkPostReadParams{
+		UserID: currentUser.ID,
+		PostID: postID,
+	})
+	if err != nil {
+		return err
+	}
+	if marked == 0 {
+		return fmt.Errorf("no post with id %s", postID)
+	}
+
+	slog.Info("post marked as read", "id", postID)
+	return nil
+}
+
+// HandlerMarkRead marks many posts as read at once: those of a feed, those
+// published before a date, or everything.
+func HandlerMarkRead(s *State, cmd Command, currentUser database.User) error {
+	usage := fmt.Errorf("usage: %s --feed <url> | --before <date> | --all", cmd.Name)
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	feedURL := fs.String("feed", "", "only mark the posts of this feed")
+	before := fs.String("before", "", "only mark the posts published before this date")
+	all := fs.Bool("all", false, "mark every post of the followed feeds")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil || len(args) > 0 {
+		return usage
+	}
+	// --all has to be explicit, and makes no sense with a filter
+	if *all == (*feedURL != "" || *before != "") {
+		return usage
+	}
+
+	params := database.MarkPostsReadParams{UserID: currentUser.ID}
+	if *feedURL != "" {
+		feed, err := s.Db.GetFeedByUrl(context.Background(), *feedURL)
+		if err != nil {
+			return fmt.Errorf("no feed with url %q: %w", *feedURL, err)
+		}
+		params.FeedID = uuid.NullUUID{UUID: feed.ID, Valid: true}
+	}
+	if params.Before, err = parseNullDate(*before); err != nil {
+		return err
+	}
+
+	marked, err := s.Db.MarkPostsRead(context.Background(), params)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("posts marked as read", "count", marked)
+	return nil
+}
+
+func HandlerRevisions(s *State, cmd Command) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <post_id>", cmd.Name)
+	}
+
+	postID, err := uuid.Parse(cmd.Args[0])
+	if err != nil {
+		return fmt.Errorf("invalid post id %q: %w", cmd.Args[0], err)
 	}
 
-	posts, err := s.Db.GetPostsByUser(context.Background(), database.GetPostsByUserParams{
+	revisions, err := s.Db.GetPostRevisions(context.Background(), postID)
+	if err != nil {
+		return err
+	}
+
+	if len(revisions) == 0 {
+		slog.Info("post was never updated", "id", postID)
+		return nil
+	}
+	for _, revision := range revisions {
+		slog.Info("revision", "replaced_at", revision.CreatedAt, "title", revision.Title, "url", revision.Url, "description", revision.Description)
+	}
+	return nil
+}
+
+func HandlerStar(s *State, cmd Command, currentUser database.User) error {
+	return setPostStarred(s, cmd, currentUser, true)
+}
+
+func HandlerUnstar(s *State, cmd Command, currentUser database.User) error {
+	return setPostStarred(s, cmd, currentUser, false)
+}
+
+func setPostStarred(s *State, cmd Command, currentUser database.User, starred bool) error {
+	postID, err := parsePostID(cmd)
+	if err != nil {
+		return err
+	}
+
+	updated, err := s.Db.SetPostStarred(context.Background(), database.SetPostStarredParams{
+		UserID:  currentUser.ID,
+		Starred: starred,
+		PostID:  postID,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("no post with id %s", postID)
+	}
+
+	slog.Info("post updated", "id", postID, "starred", starred)
+	return nil
+}
+
+// HandlerSave adds a post to the read-later queue, see HandlerSaved.
+func HandlerSave(s *State, cmd Command, currentUser database.User) error {
+	return setPostSaved(s, cmd, currentUser, true)
+}
+
+func HandlerUnsave(s *State, cmd Command, currentUser database.User) error {
+	return setPostSaved(s, cmd, currentUser, false)
+}
+
+func setPostSaved(s *State, cmd Command, currentUser database.User, saved bool) error {
+	postID, err := parsePostID(cmd)
+	if err != nil {
+		return err
+	}
+
+	updated, err := s.Db.SetPostSaved(context.Background(), database.SetPostSavedParams{
+		UserID: currentUser.ID,
+		Saved:  saved,
+		PostID: postID,
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("no post with id %s", postID)
+	}
+
+	slog.Info("post updated", "id", postID, "saved", saved)
+	return nil
+}
+
+func HandlerStarred(s *State, cmd Command, currentUser database.User) error {
+	limit, ok := parseLimit(cmd.Args, DefaultListLimit)
+	if !ok {
+		return fmt.Errorf("usage: %s [limit]", cmd.Name)
+	}
+
+	posts, err := s.Db.GetStarredPosts(context.Background(), database.GetStarredPostsParams{
+		UserID: currentUser.ID,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(posts) == 0 {
+		slog.Info("no starred posts")
+		return nil
+	}
+	for _, post := range posts {
+		slog.Info("post", "id", post.ID, "feed", post.FeedName, "title", post.Title, "url", post.Url, "published_at", post.PublishedAt, "starred_at", post.StarredAt.Time)
+	}
+	return nil
+}
+
+// HandlerSaved lists the read-later queue, oldest saved first.
+func HandlerSaved(s *State, cmd Command, currentUser database.User) error {
+	limit, ok := parseLimit(cmd.Args, DefaultListLimit)
+	if !ok {
+		return fmt.Errorf("usage: %s [limit]", cmd.Name)
+	}
+
+	posts, err := s.Db.GetSavedPosts(context.Background(), database.GetSavedPostsParams{
 		UserID: currentUser.ID,
 		Limit:  int32(limit),
 	})
@@ -35,11 +351,70 @@ func HandlerBrowse(s *State, cmd Command, currentUser database.User) error {
 	}
 
 	if len(posts) == 0 {
-		slog.Info("no posts available")
+		slog.Info("no saved posts")
 		return nil
 	}
 	for _, post := range posts {
-		slog.Info("post", "title", post.Title, "url", post.Url, "description", post.Description, "published_at", post.PublishedAt)
+		slog.Info("post", "id", post.ID, "feed", post.FeedName, "title", post.Title, "url", post.Url, "published_at", post.PublishedAt, "saved_at", post.SavedAt.Time, "read", post.Read)
+	}
+	return nil
+}
+
+// HandlerPrune deletes the posts published longer ago than max_age, except
+// those someone starred or saved. their guids are remembered, so posts still
+// listed by their feed aren't stored again on the next fetch.
+func HandlerPrune(s *State, cmd Command) error {
+	if len(cmd.Args) != 1 {
+		return fmt.Errorf("usage: %s <max_age, e.g. 90d or 720h>", cmd.Name)
+	}
+
+	maxAge, err := parseAge(cmd.Args[0])
+	if err != nil || maxAge <= 0 {
+		return fmt.Errorf("invalid age %q, expected something like 90d or 720h", cmd.Args[0])
 	}
+
+	pruned, err := s.Db.PrunePosts(context.Background(), time.Now().UTC().Add(-maxAge))
+	if err != nil {
+		return err
+	}
+
+	slog.Info("posts pruned", "count", pruned, "older_than", cmd.Args[0])
 	return nil
 }
+
+// parsePostID reads the post id, the only argument of cmd.
+func parsePostID(cmd Command) (uuid.UUID, error) {
+	if len(cmd.Args) != 1 {
+		return uuid.Nil, fmt.Errorf("usage: %s <post_id>", cmd.Name)
+	}
+	postID, err := uuid.Parse(cmd.Args[0])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid post id %q: %w", cmd.Args[0], err)
+	}
+	return postID, nil
+}
+
+// parseLimit reads the optional limit argument of listing commands.
+func parseLimit(args []string, fallback int) (int, bool) {
+	if len(args) == 0 {
+		return fallback, true
+	}
+	if len(args) > 1 {
+		return 0, false
+	}
+	limit, err := strconv.Atoi(args[0])
+	return limit, err == nil && limit > 0
+}
+
+// parseAge reads a duration, in days ("30d") on top of the units
+// time.ParseDuration knows.
+func parseAge(value string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(value)
+}
@@ -0,0 +1,67 @@
+package cli
+
+import (
+	"encoding/base64"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestParseNullDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+		valid bool
+	}{
+		{"", time.Time{}, false},
+		{"2006-01-02", time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC), true},
+		{"2006-01-02T17:04:05+02:00", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC), true},
+		{"Mon, 02 Jan 2006 10:04:05 EST", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		got, err := parseNullDate(tt.value)
+		if err != nil {
+			t.Errorf("parseNullDate(%q) returned error: %v", tt.value, err)
+			continue
+		}
+		if got.Valid != tt.valid || got.Time != tt.want {
+			t.Errorf("parseNullDate(%q) = %v, want %v", tt.value, got.Time, tt.want)
+		}
+	}
+
+	if _, err := parseNullDate("last week"); err == nil {
+		t.Error("parseNullDate accepted an invalid date")
+	}
+}
+
+func TestBrowseCursorRoundTrip(t *testing.T) {
+	cursors := []browseCursor{
+		{Sort: "published", Time: time.Date(2024, time.May, 5, 10, 30, 0, 123456789, time.UTC), ID: uuid.New()},
+		{Sort: "fetched", Time: time.Date(1999, time.December, 31, 23, 59, 59, 0, time.UTC), ID: uuid.New()},
+		{Sort: "published", Time: time.Time{}.UTC(), ID: uuid.Nil},
+	}
+	for _, cursor := range cursors {
+		got, err := parseBrowseCursor(cursor.String())
+		if err != nil {
+			t.Errorf("parseBrowseCursor(%v) returned error: %v", cursor, err)
+			continue
+		}
+		if got.Sort != cursor.Sort || !got.Time.Equal(cursor.Time) || got.ID != cursor.ID {
+			t.Errorf("round trip of %+v gave %+v", cursor, got)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"not base64!",
+		base64.RawURLEncoding.EncodeToString([]byte("published|2024-05-05T10:30:00Z")),
+		base64.RawURLEncoding.EncodeToString([]byte("published|yesterday|" + uuid.NewString())),
+		base64.RawURLEncoding.EncodeToString([]byte("published|2024-05-05T10:30:00Z|not-a-uuid")),
+	}
+	for _, value := range invalid {
+		if _, err := parseBrowseCursor(value); err == nil {
+			t.Errorf("parseBrowseCursor(%q) accepted an invalid cursor", value)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"errors"
+	"time"
+
+	"github.com/grainme/gator/internal/database"
+	"github.com/grainme/gator/internal/rss"
+)
+
+const (
+	DefaultMaxFetchInterval = 24 * time.Hour
+	// used when no agg options apply, e.g. scheduling a feed added by addfeed
+	DefaultMinFetchInterval = 30 * time.Minute
+)
+
+// nextFetchAt schedules the next fetch of a feed: never sooner than
+// minInterval or than the publisher's own hint, and about twice per observed
+// gap between posts so dormant feeds back off, up to maxInterval.
+func nextFetchAt(now time.Time, feed *rss.RSSFeed, minInterval, maxInterval time.Duration) time.Time {
+	interval := max(minInterval, feed.UpdateHint())
+	if gap, ok := feed.PostingInterval(now); ok {
+		interval = max(interval, gap/2)
+	}
+	interval = min(interval, max(maxInterval, minInterval))
+
+	next := now.Add(interval)
+	// skipHours/skipDays are whole hours, skip a full week at most so a feed
+	// skipping every hour still gets fetched eventually
+	for range 7 * 24 {
+		if !feed.Skips(next) {
+			break
+		}
+		next = next.Truncate(time.Hour).Add(time.Hour)
+	}
+	return next
+}
+
+// keepInterval schedules the next fetch as far out as the last one was, for
+// when there's no new document to learn from (e.g. 304 Not Modified). after
+// failures the last gap was a retry backoff, not worth keeping.
+func keepInterval(now time.Time, feed database.Feed, minInterval, maxInterval time.Duration) time.Time {
+	interval := minInterval
+	if feed.NextFetchAt.Valid && feed.LastFetchedAt.Valid && feed.ConsecutiveFailures == 0 {
+		interval = feed.NextFetchAt.Time.Sub(feed.LastFetchedAt.Time)
+	}
+	return now.Add(min(max(interval, minInterval), max(maxInterval, minInterval)))
+}
+
+// retryFetchAt schedules the next fetch after a failed one: minInterval
+// doubled for each failure in a row, up to maxInterval, or the delay the
+// server asked for when it is longer.
+func retryFetchAt(now time.Time, err error, failures int32, minInterval, maxInterval time.Duration) time.Time {
+	interval := minInterval
+	for i := int32(1); i < failures && interval < maxInterval; i++ {
+		interval *= 2
+	}
+	interval = min(interval, max(maxInterval, minInterval))
+
+	var fetchErr *rss.FetchError
+	if errors.As(err, &fetchErr) {
+		interval = max(interval, fetchErr.RetryAfter)
+	}
+	return now.Add(interval)
+}
@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"database/sql"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/grainme/gator/internal/database"
+	"github.com/grainme/gator/internal/rss"
+)
+
+func TestNextFetchAt(t *testing.T) {
+	// a Sunday, 10:30 UTC
+	now := time.Date(2024, time.May, 5, 10, 30, 0, 0, time.UTC)
+	feed := func(setup func(*rss.RSSFeed)) *rss.RSSFeed {
+		var f rss.RSSFeed
+		if setup != nil {
+			setup(&f)
+		}
+		return &f
+	}
+
+	tests := []struct {
+		name     string
+		feed     *rss.RSSFeed
+		min, max time.Duration
+		want     time.Time
+	}{
+		{"no hints", feed(nil), time.Hour, 24 * time.Hour, now.Add(time.Hour)},
+		{"ttl above min", feed(func(f *rss.RSSFeed) { f.Channel.TTL = "180" }), time.Hour, 24 * time.Hour, now.Add(3 * time.Hour)},
+		{"ttl below min", feed(func(f *rss.RSSFeed) { f.Channel.TTL = "5" }), time.Hour, 24 * time.Hour, now.Add(time.Hour)},
+		{"hint clamped to max", feed(func(f *rss.RSSFeed) { f.Channel.UpdatePeriod = "weekly" }), time.Hour, 24 * time.Hour, now.Add(24 * time.Hour)},
+		{"min above max", feed(nil), 2 * time.Hour, time.Hour, now.Add(2 * time.Hour)},
+		{"half the posting interval", feed(func(f *rss.RSSFeed) {
+			f.Channel.Item = []rss.RSSItem{{PubDate: now.Add(-8 * time.Hour).Format(time.RFC3339)}}
+		}), time.Hour, 24 * time.Hour, now.Add(4 * time.Hour)},
+		{"skipped hours are jumped over", feed(func(f *rss.RSSFeed) { f.Channel.SkipHours = []string{"11", "12"} }), time.Hour, 24 * time.Hour, time.Date(2024, time.May, 5, 13, 0, 0, 0, time.UTC)},
+		{"skipped days are jumped over", feed(func(f *rss.RSSFeed) { f.Channel.SkipDays = []string{"Sunday"} }), time.Hour, 24 * time.Hour, time.Date(2024, time.May, 6, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := nextFetchAt(now, tt.feed, tt.min, tt.max); !got.Equal(tt.want) {
+			t.Errorf("%s: nextFetchAt = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNextFetchAtEveryHourSkipped(t *testing.T) {
+	now := time.Date(2024, time.May, 5, 10, 30, 0, 0, time.UTC)
+	var feed rss.RSSFeed
+	for hour := range 24 {
+		feed.Channel.SkipHours = append(feed.Channel.SkipHours, strconv.Itoa(hour))
+	}
+
+	// gives up after a week instead of looping forever
+	got := nextFetchAt(now, &feed, time.Hour, 24*time.Hour)
+	if want := time.Date(2024, time.May, 12, 11, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("nextFetchAt = %v, want %v", got, want)
+	}
+}
+
+func TestRetryFetchAt(t *testing.T) {
+	now := time.Date(2024, time.May, 5, 10, 30, 0, 0, time.UTC)
+	fetchErr := errors.New("connection refused")
+
+	tests := []struct {
+		name     string
+		err      error
+		failures int32
+		want     time.Duration
+	}{
+		{"first failure", fetchErr, 1, time.Hour},
+		{"doubles per failure", fetchErr, 3, 4 * time.Hour},
+		{"capped at max", fetchErr, 10, 24 * time.Hour},
+		{"retry-after is longer", &rss.FetchError{Kind: rss.ErrorClient, StatusCode: 429, RetryAfter: 6 * time.Hour}, 1, 6 * time.Hour},
+		{"retry-after is shorter", &rss.FetchError{Kind: rss.ErrorClient, StatusCode: 429, RetryAfter: time.Minute}, 2, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryFetchAt(now, tt.err, tt.failures, time.Hour, 24*time.Hour); !got.Equal(now.Add(tt.want)) {
+			t.Errorf("%s: retryFetchAt = %v, want %v", tt.name, got, now.Add(tt.want))
+		}
+	}
+}
+
+func TestKeepInterval(t *testing.T) {
+	now := time.Date(2024, time.May, 5, 10, 30, 0, 0, time.UTC)
+	lastFetched := sql.NullTime{Time: now.Add(-6 * time.Hour), Valid: true}
+	scheduled := func(gap time.Duration, failures int32) database.Feed {
+		return database.Feed{
+			LastFetchedAt:       lastFetched,
+			NextFetchAt:         sql.NullTime{Time: lastFetched.Time.Add(gap), Valid: true},
+			ConsecutiveFailures: failures,
+		}
+	}
+
+	tests := []struct {
+		name string
+		feed database.Feed
+		want time.Duration
+	}{
+		{"never fetched", database.Feed{}, time.Hour},
+		{"keeps the last gap", scheduled(6*time.Hour, 0), 6 * time.Hour},
+		{"gap below min", scheduled(time.Minute, 0), time.Hour},
+		{"gap above max", scheduled(48*time.Hour, 0), 24 * time.Hour},
+		{"gap was a retry backoff", scheduled(16*time.Hour, 5), time.Hour},
+	}
+	for _, tt := range tests {
+		if got := keepInterval(now, tt.feed, time.Hour, 24*time.Hour); !got.Equal(now.Add(tt.want)) {
+			t.Errorf("%s: keepInterval = %v, want %v", tt.name, got, now.Add(tt.want))
+		}
+	}
+}
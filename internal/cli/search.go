@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+
+	"github.com/grainme/gator/internal/database"
+)
+
+// HandlerSearch runs a full-text search over the posts of the followed feeds,
+// best matches first. "quoted words" must appear next to each other and a
+// trailing * matches every word starting with the prefix.
+func HandlerSearch(s *State, cmd Command, currentUser database.User) error {
+	usage := fmt.Errorf(`usage: %s <query> [--limit n] (e.g. %s 'golang "error handling" gen*')`, cmd.Name, cmd.Name)
+
+	fs := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	limit := fs.Int("limit", DefaultListLimit, "show at most this many posts")
+	args, err := parseFlags(fs, cmd.Args)
+	if err != nil || len(args) == 0 || *limit <= 0 {
+		return usage
+	}
+
+	query := tsQuery(strings.Join(args, " "))
+	if query == "" {
+		return usage
+	}
+
+	posts, err := s.Db.SearchPosts(context.Background(), database.SearchPostsParams{
+		Query:     query,
+		UserID:    currentUser.ID,
+		PostLimit: int32(*limit),
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(posts) == 0 {
+		slog.Info("no posts found", "query", query)
+		return nil
+	}
+	for _, post := range posts {
+		slog.Info("post", "id", post.ID, "feed", post.FeedName, "title", post.Title, "url", post.Url, "published_at", post.PublishedAt, "rank", post.Rank, "snippet", post.Snippet)
+	}
+	return nil
+}
+
+// tsQuery turns what the user typed into a to_tsquery expression where every
+// term is required. anything but letters and digits is dropped so the input
+// can't break the tsquery syntax.
+func tsQuery(input string) string {
+	var terms []string
+	for input = strings.TrimSpace(input); input != ""; input = strings.TrimSpace(input) {
+		var chunk string
+		if input[0] == '"' {
+			end := strings.IndexByte(input[1:], '"')
+			if end < 0 {
+				chunk, input = input[1:], ""
+			} else {
+				chunk, input = input[1:end+1], input[end+2:]
+			}
+		} else {
+			end := strings.IndexFunc(input, func(r rune) bool { return unicode.IsSpace(r) || r == '"' })
+			if end < 0 {
+				end = len(input)
+			}
+			chunk, input = input[:end], input[end:]
+		}
+		if term := tsPhrase(chunk); term != "" {
+			terms = append(terms, term)
+		}
+	}
+	return strings.Join(terms, " & ")
+}
+
+// tsPhrase joins the words of chunk so they only match side by side, like
+// "e-mail" or a quoted phrase. a trailing * makes the last word a prefix.
+func tsPhrase(chunk string) string {
+	words := strings.FieldsFunc(chunk, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) == 0 {
+		return ""
+	}
+	if strings.HasSuffix(chunk, "*") {
+		words[len(words)-1] += ":*"
+	}
+	phrase := strings.Join(words, " <-> ")
+	if len(words) > 1 {
+		phrase = "(" + phrase + ")"
+	}
+	return phrase
+}
@@ -0,0 +1,27 @@
+package cli
+
+import "testing"
+
+func TestTSQuery(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"golang", "golang"},
+		{"go generics", "go & generics"},
+		{`"error handling" go`, "(error <-> handling) & go"},
+		{"gen*", "gen:*"},
+		{`"go gen*"`, "(go <-> gen:*)"},
+		{"e-mail", "(e <-> mail)"},
+		{`"unterminated phrase`, "(unterminated <-> phrase)"},
+		{"café  crème", "café & crème"},
+		{"'; DROP TABLE posts; --", "DROP & TABLE & posts"},
+		{"a & b | !c", "a & b & c"},
+		{`  "" * -- `, ""},
+	}
+	for _, tt := range tests {
+		if got := tsQuery(tt.input); got != tt.want {
+			t.Errorf("tsQuery(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -17,12 +17,12 @@ const createFeedFollow = `-- name: CreateFeedFollow :one
 WITH
   inserted_feed_follow AS (
     INSERT INTO
-      feed_follows (id, created_at, updated_at, user_id, feed_id)
+      feed_follows (id, created_at, updated_at, user_id, feed_id, category)
     VALUES
-      ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at, user_id, feed_id
+      ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, user_id, feed_id, category
   )
 SELECT
-  inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id,
+  inserted_feed_follow.id, inserted_feed_follow.created_at, inserted_feed_follow.updated_at, inserted_feed_follow.user_id, inserted_feed_follow.feed_id, inserted_feed_follow.category,
   feeds.name as feedName,
   users.name as userName
 FROM
@@ -37,6 +37,7 @@ type CreateFeedFollowParams struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 }
 
 type CreateFeedFollowRow struct {
@@ -45,6 +46,7 @@ type CreateFeedFollowRow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
 	Feedname  string
 	Username  string
 }
@@ -56,6 +58,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		arg.UpdatedAt,
 		arg.UserID,
 		arg.FeedID,
+		arg.Category,
 	)
 	var i CreateFeedFollowRow
 	err := row.Scan(
@@ -64,6 +67,7 @@ func (q *Queries) CreateFeedFollow(ctx context.Context, arg CreateFeedFollowPara
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Category,
 		&i.Feedname,
 		&i.Username,
 	)
@@ -92,7 +96,7 @@ func (q *Queries) DeleteByUserIdAndFeedId(ctx context.Context, arg DeleteByUserI
 
 const getFeedFollowByFeedId = `-- name: GetFeedFollowByFeedId :one
 SELECT
-  feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_id, feeds.id, feeds.created_at, feeds.updated_at, name, url, feeds.user_id, last_fetched_at,
+  feed_follows.id, feed_follows.created_at, feed_follows.updated_at, feed_follows.user_id, feed_id, category, feeds.id, feeds.created_at, feeds.updated_at, name, url, feeds.user_id, last_fetched_at, claimed_by, locked_until, next_fetch_at, etag, last_modified, last_error, last_error_at, consecutive_failures, last_success_at, last_status_code, paused_at, site_url, description, icon_url, language,
   feeds.name as feedName
 FROM
   feed_follows
@@ -102,19 +106,35 @@ WHERE
 `
 
 type GetFeedFollowByFeedIdRow struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	UserID        uuid.UUID
-	FeedID        uuid.UUID
-	ID_2          uuid.UUID
-	CreatedAt_2   time.Time
-	UpdatedAt_2   time.Time
-	Name          string
-	Url           string
-	UserID_2      uuid.UUID
-	LastFetchedAt sql.NullTime
-	Feedname      string
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	UserID              uuid.UUID
+	FeedID              uuid.UUID
+	Category            sql.NullString
+	ID_2                uuid.UUID
+	CreatedAt_2         time.Time
+	UpdatedAt_2         time.Time
+	Name                string
+	Url                 string
+	UserID_2            uuid.UUID
+	LastFetchedAt       sql.NullTime
+	ClaimedBy           sql.NullString
+	LockedUntil         sql.NullTime
+	NextFetchAt         sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	LastError           sql.NullString
+	LastErrorAt         sql.NullTime
+	ConsecutiveFailures int32
+	LastSuccessAt       sql.NullTime
+	LastStatusCode      sql.NullInt32
+	PausedAt            sql.NullTime
+	SiteUrl             sql.NullString
+	Description         sql.NullString
+	IconUrl             sql.NullString
+	Language            sql.NullString
+	Feedname            string
 }
 
 func (q *Queries) GetFeedFollowByFeedId(ctx context.Context, feedID uuid.UUID) (GetFeedFollowByFeedIdRow, error) {
@@ -126,6 +146,7 @@ func (q *Queries) GetFeedFollowByFeedId(ctx context.Context, feedID uuid.UUID) (
 		&i.UpdatedAt,
 		&i.UserID,
 		&i.FeedID,
+		&i.Category,
 		&i.ID_2,
 		&i.CreatedAt_2,
 		&i.UpdatedAt_2,
@@ -133,6 +154,21 @@ func (q *Queries) GetFeedFollowByFeedId(ctx context.Context, feedID uuid.UUID) (
 		&i.Url,
 		&i.UserID_2,
 		&i.LastFetchedAt,
+		&i.ClaimedBy,
+		&i.LockedUntil,
+		&i.NextFetchAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.LastStatusCode,
+		&i.PausedAt,
+		&i.SiteUrl,
+		&i.Description,
+		&i.IconUrl,
+		&i.Language,
 		&i.Feedname,
 	)
 	return i, err
@@ -140,7 +176,7 @@ func (q *Queries) GetFeedFollowByFeedId(ctx context.Context, feedID uuid.UUID) (
 
 const getFeedFollowsForUser = `-- name: GetFeedFollowsForUser :many
 SELECT
-  id, created_at, updated_at, user_id, feed_id
+  id, created_at, updated_at, user_id, feed_id, category
 FROM
   feed_follows
 WHERE
@@ -162,6 +198,58 @@ func (q *Queries) GetFeedFollowsForUser(ctx context.Context, userID uuid.UUID) (
 			&i.UpdatedAt,
 			&i.UserID,
 			&i.FeedID,
+			&i.Category,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFollowedFeedsForUser = `-- name: GetFollowedFeedsForUser :many
+SELECT
+  feeds.name,
+  feeds.url,
+  feeds.site_url,
+  feed_follows.category
+FROM
+  feed_follows
+  INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE
+  feed_follows.user_id = $1
+ORDER BY
+  feed_follows.category NULLS FIRST,
+  feeds.name
+`
+
+type GetFollowedFeedsForUserRow struct {
+	Name     string
+	Url      string
+	SiteUrl  sql.NullString
+	Category sql.NullString
+}
+
+func (q *Queries) GetFollowedFeedsForUser(ctx context.Context, userID uuid.UUID) ([]GetFollowedFeedsForUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, getFollowedFeedsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetFollowedFeedsForUserRow
+	for rows.Next() {
+		var i GetFollowedFeedsForUserRow
+		if err := rows.Scan(
+			&i.Name,
+			&i.Url,
+			&i.SiteUrl,
+			&i.Category,
 		); err != nil {
 			return nil, err
 		}
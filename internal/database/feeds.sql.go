@@ -7,16 +7,103 @@ package database
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const claimFeedsToFetch = `-- name: ClaimFeedsToFetch :many
+UPDATE feeds
+SET
+  updated_at = Now(),
+  claimed_by = $1::text,
+  locked_until = Now() + make_interval(secs => $2::float8)
+WHERE
+  feeds.id IN (
+    SELECT
+      id
+    FROM
+      feeds
+    WHERE
+      (
+        next_fetch_at IS NULL
+        OR next_fetch_at <= Now()
+      )
+      AND (
+        locked_until IS NULL
+        OR locked_until < Now()
+      )
+      AND paused_at IS NULL
+    ORDER BY
+      next_fetch_at NULLS FIRST
+    LIMIT
+      $3
+    FOR UPDATE
+      SKIP LOCKED
+  ) RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, claimed_by, locked_until, next_fetch_at, etag, last_modified, last_error, last_error_at, consecutive_failures, last_success_at, last_status_code, paused_at, site_url, description, icon_url, language
+`
+
+type ClaimFeedsToFetchParams struct {
+	ClaimedBy    string
+	LeaseSeconds float64
+	BatchSize    int32
+}
+
+// SKIP LOCKED lets several aggregators claim from the same table without
+// waiting on each other, the lease hands the feed back if its claimer dies.
+// leases are timed with the database clock, the one they are checked against.
+func (q *Queries) ClaimFeedsToFetch(ctx context.Context, arg ClaimFeedsToFetchParams) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, claimFeedsToFetch, arg.ClaimedBy, arg.LeaseSeconds, arg.BatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.ClaimedBy,
+			&i.LockedUntil,
+			&i.NextFetchAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.LastStatusCode,
+			&i.PausedAt,
+			&i.SiteUrl,
+			&i.Description,
+			&i.IconUrl,
+			&i.Language,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createFeed = `-- name: CreateFeed :one
 INSERT INTO
   feeds (id, created_at, updated_at, name, url, user_id)
 VALUES
-  ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at
+  ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, claimed_by, locked_until, next_fetch_at, etag, last_modified, last_error, last_error_at, consecutive_failures, last_success_at, last_status_code, paused_at, site_url, description, icon_url, language
 `
 
 type CreateFeedParams struct {
@@ -46,13 +133,28 @@ func (q *Queries) CreateFeed(ctx context.Context, arg CreateFeedParams) (Feed, e
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.ClaimedBy,
+		&i.LockedUntil,
+		&i.NextFetchAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.LastStatusCode,
+		&i.PausedAt,
+		&i.SiteUrl,
+		&i.Description,
+		&i.IconUrl,
+		&i.Language,
 	)
 	return i, err
 }
 
 const getAllFeeds = `-- name: GetAllFeeds :many
 SELECT
-  id, created_at, updated_at, name, url, user_id, last_fetched_at
+  id, created_at, updated_at, name, url, user_id, last_fetched_at, claimed_by, locked_until, next_fetch_at, etag, last_modified, last_error, last_error_at, consecutive_failures, last_success_at, last_status_code, paused_at, site_url, description, icon_url, language
 FROM
   feeds
 `
@@ -74,6 +176,80 @@ func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
 			&i.Url,
 			&i.UserID,
 			&i.LastFetchedAt,
+			&i.ClaimedBy,
+			&i.LockedUntil,
+			&i.NextFetchAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.LastStatusCode,
+			&i.PausedAt,
+			&i.SiteUrl,
+			&i.Description,
+			&i.IconUrl,
+			&i.Language,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getBrokenFeeds = `-- name: GetBrokenFeeds :many
+SELECT
+  id, created_at, updated_at, name, url, user_id, last_fetched_at, claimed_by, locked_until, next_fetch_at, etag, last_modified, last_error, last_error_at, consecutive_failures, last_success_at, last_status_code, paused_at, site_url, description, icon_url, language
+FROM
+  feeds
+WHERE
+  consecutive_failures > 0
+  OR paused_at IS NOT NULL
+ORDER BY
+  paused_at NULLS LAST,
+  consecutive_failures DESC
+`
+
+func (q *Queries) GetBrokenFeeds(ctx context.Context) ([]Feed, error) {
+	rows, err := q.db.QueryContext(ctx, getBrokenFeeds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Feed
+	for rows.Next() {
+		var i Feed
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Name,
+			&i.Url,
+			&i.UserID,
+			&i.LastFetchedAt,
+			&i.ClaimedBy,
+			&i.LockedUntil,
+			&i.NextFetchAt,
+			&i.Etag,
+			&i.LastModified,
+			&i.LastError,
+			&i.LastErrorAt,
+			&i.ConsecutiveFailures,
+			&i.LastSuccessAt,
+			&i.LastStatusCode,
+			&i.PausedAt,
+			&i.SiteUrl,
+			&i.Description,
+			&i.IconUrl,
+			&i.Language,
 		); err != nil {
 			return nil, err
 		}
@@ -90,13 +266,26 @@ func (q *Queries) GetAllFeeds(ctx context.Context) ([]Feed, error) {
 
 const getFeedByUrl = `-- name: GetFeedByUrl :one
 SELECT
-  id, created_at, updated_at, name, url, user_id, last_fetched_at
+  id, created_at, updated_at, name, url, user_id, last_fetched_at, claimed_by, locked_until, next_fetch_at, etag, last_modified, last_error, last_error_at, consecutive_failures, last_success_at, last_status_code, paused_at, site_url, description, icon_url, language
 FROM
   feeds
 WHERE
-  url = $1
+  feeds.url = $1
+  OR feeds.id IN (
+    SELECT
+      feed_id
+    FROM
+      feed_aliases
+    WHERE
+      feed_aliases.url = $1
+  )
+ORDER BY
+  feeds.url = $1 DESC
+LIMIT
+  1
 `
 
+// also finds feeds by the urls they had before moving
 func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 	row := q.db.QueryRowContext(ctx, getFeedByUrl, url)
 	var i Feed
@@ -108,23 +297,148 @@ func (q *Queries) GetFeedByUrl(ctx context.Context, url string) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.ClaimedBy,
+		&i.LockedUntil,
+		&i.NextFetchAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.LastStatusCode,
+		&i.PausedAt,
+		&i.SiteUrl,
+		&i.Description,
+		&i.IconUrl,
+		&i.Language,
 	)
 	return i, err
 }
 
-const getNextFeedToFetch = `-- name: GetNextFeedToFetch :one
+const getFeedHistory = `-- name: GetFeedHistory :many
 SELECT
-  id, created_at, updated_at, name, url, user_id, last_fetched_at
+  id, created_at, feed_id, event, detail
 FROM
-  feeds
+  feed_history
+WHERE
+  feed_id = $1
 ORDER BY
-  last_fetched_at NULLS FIRST
-LIMIT
-  1
+  created_at DESC
 `
 
-func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
-	row := q.db.QueryRowContext(ctx, getNextFeedToFetch)
+func (q *Queries) GetFeedHistory(ctx context.Context, feedID uuid.UUID) ([]FeedHistory, error) {
+	rows, err := q.db.QueryContext(ctx, getFeedHistory, feedID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []FeedHistory
+	for rows.Next() {
+		var i FeedHistory
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.FeedID,
+			&i.Event,
+			&i.Detail,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFeedFetched = `-- name: MarkFeedFetched :exec
+UPDATE feeds
+SET
+  updated_at = Now(),
+  last_fetched_at = Now(),
+  claimed_by = NULL,
+  locked_until = NULL,
+  next_fetch_at = Now() + make_interval(secs => $1::float8)
+WHERE
+  feeds.id = $2
+  AND claimed_by = $3::text
+`
+
+type MarkFeedFetchedParams struct {
+	NextFetchIn float64
+	ID          uuid.UUID
+	ClaimedBy   string
+}
+
+// next_fetch_at is relative to the database clock, like last_fetched_at and
+// the due check in ClaimFeedsToFetch
+func (q *Queries) MarkFeedFetched(ctx context.Context, arg MarkFeedFetchedParams) error {
+	_, err := q.db.ExecContext(ctx, markFeedFetched, arg.NextFetchIn, arg.ID, arg.ClaimedBy)
+	return err
+}
+
+const moveFeedUrl = `-- name: MoveFeedUrl :one
+WITH
+  old_feed AS (
+    SELECT
+      feeds.id,
+      feeds.url
+    FROM
+      feeds
+    WHERE
+      feeds.id = $1
+  ),
+  alias AS (
+    INSERT INTO
+      feed_aliases (url, feed_id, created_at)
+    SELECT
+      old_feed.url,
+      old_feed.id,
+      Now()
+    FROM
+      old_feed
+    ON CONFLICT (url) DO NOTHING
+  ),
+  moved_back AS (
+    DELETE FROM feed_aliases
+    WHERE
+      feed_aliases.url = $2::text
+      AND feed_aliases.feed_id = $1
+  ),
+  history AS (
+    INSERT INTO
+      feed_history (id, created_at, feed_id, event, detail)
+    SELECT
+      $3::uuid,
+      Now(),
+      old_feed.id,
+      'moved',
+      old_feed.url || ' -> ' || $2::text
+    FROM
+      old_feed
+  )
+UPDATE feeds
+SET
+  updated_at = Now(),
+  url = $2::text
+WHERE
+  feeds.id = $1 RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, claimed_by, locked_until, next_fetch_at, etag, last_modified, last_error, last_error_at, consecutive_failures, last_success_at, last_status_code, paused_at, site_url, description, icon_url, language
+`
+
+type MoveFeedUrlParams struct {
+	ID        uuid.UUID
+	NewUrl    string
+	HistoryID uuid.UUID
+}
+
+// the old url is kept as an alias and the move is logged in feed_history
+func (q *Queries) MoveFeedUrl(ctx context.Context, arg MoveFeedUrlParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, moveFeedUrl, arg.ID, arg.NewUrl, arg.HistoryID)
 	var i Feed
 	err := row.Scan(
 		&i.ID,
@@ -134,20 +448,214 @@ func (q *Queries) GetNextFeedToFetch(ctx context.Context) (Feed, error) {
 		&i.Url,
 		&i.UserID,
 		&i.LastFetchedAt,
+		&i.ClaimedBy,
+		&i.LockedUntil,
+		&i.NextFetchAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.LastStatusCode,
+		&i.PausedAt,
+		&i.SiteUrl,
+		&i.Description,
+		&i.IconUrl,
+		&i.Language,
 	)
 	return i, err
 }
 
-const markFeedFetched = `-- name: MarkFeedFetched :exec
+const recordFeedError = `-- name: RecordFeedError :one
 UPDATE feeds
 SET
-  updated_at = Now(),
-  last_fetched_at = Now()
+  last_error = $1,
+  last_error_at = Now(),
+  last_status_code = $2,
+  consecutive_failures = consecutive_failures + 1,
+  paused_at = CASE
+    WHEN paused_at IS NULL
+    AND $3::int > 0
+    AND consecutive_failures + 1 >= $3::int THEN Now()
+    ELSE paused_at
+  END
+WHERE
+  feeds.id = $4 RETURNING id, created_at, updated_at, name, url, user_id, last_fetched_at, claimed_by, locked_until, next_fetch_at, etag, last_modified, last_error, last_error_at, consecutive_failures, last_success_at, last_status_code, paused_at, site_url, description, icon_url, language
+`
+
+type RecordFeedErrorParams struct {
+	LastError      sql.NullString
+	LastStatusCode sql.NullInt32
+	MaxFailures    int32
+	ID             uuid.UUID
+}
+
+// the feed is paused once it failed max_failures times in a row (0 never pauses)
+func (q *Queries) RecordFeedError(ctx context.Context, arg RecordFeedErrorParams) (Feed, error) {
+	row := q.db.QueryRowContext(ctx, recordFeedError,
+		arg.LastError,
+		arg.LastStatusCode,
+		arg.MaxFailures,
+		arg.ID,
+	)
+	var i Feed
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Name,
+		&i.Url,
+		&i.UserID,
+		&i.LastFetchedAt,
+		&i.ClaimedBy,
+		&i.LockedUntil,
+		&i.NextFetchAt,
+		&i.Etag,
+		&i.LastModified,
+		&i.LastError,
+		&i.LastErrorAt,
+		&i.ConsecutiveFailures,
+		&i.LastSuccessAt,
+		&i.LastStatusCode,
+		&i.PausedAt,
+		&i.SiteUrl,
+		&i.Description,
+		&i.IconUrl,
+		&i.Language,
+	)
+	return i, err
+}
+
+const recordFeedSuccess = `-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET
+  consecutive_failures = 0,
+  last_success_at = Now(),
+  last_status_code = $2
+WHERE
+  feeds.id = $1
+`
+
+type RecordFeedSuccessParams struct {
+	ID             uuid.UUID
+	LastStatusCode sql.NullInt32
+}
+
+func (q *Queries) RecordFeedSuccess(ctx context.Context, arg RecordFeedSuccessParams) error {
+	_, err := q.db.ExecContext(ctx, recordFeedSuccess, arg.ID, arg.LastStatusCode)
+	return err
+}
+
+const renewFeedLease = `-- name: RenewFeedLease :execrows
+UPDATE feeds
+SET
+  locked_until = Now() + make_interval(secs => $1::float8)
+WHERE
+  feeds.id = $2
+  AND claimed_by = $3::text
+`
+
+type RenewFeedLeaseParams struct {
+	LeaseSeconds float64
+	ID           uuid.UUID
+	ClaimedBy    string
+}
+
+// extends our lease on a claimed feed, nothing is updated once another
+// instance took it over
+func (q *Queries) RenewFeedLease(ctx context.Context, arg RenewFeedLeaseParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, renewFeedLease, arg.LeaseSeconds, arg.ID, arg.ClaimedBy)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const resumeFeed = `-- name: ResumeFeed :execrows
+UPDATE feeds
+SET
+  paused_at = NULL,
+  consecutive_failures = 0,
+  next_fetch_at = NULL
+WHERE
+  url = $1
+`
+
+func (q *Queries) ResumeFeed(ctx context.Context, url string) (int64, error) {
+	result, err := q.db.ExecContext(ctx, resumeFeed, url)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const scheduleFeed = `-- name: ScheduleFeed :exec
+UPDATE feeds
+SET
+  last_fetched_at = Now(),
+  next_fetch_at = Now() + make_interval(secs => $1::float8)
+WHERE
+  feeds.id = $2
+`
+
+type ScheduleFeedParams struct {
+	NextFetchIn float64
+	ID          uuid.UUID
+}
+
+// for feeds fetched outside of agg, see MarkFeedFetched
+func (q *Queries) ScheduleFeed(ctx context.Context, arg ScheduleFeedParams) error {
+	_, err := q.db.ExecContext(ctx, scheduleFeed, arg.NextFetchIn, arg.ID)
+	return err
+}
+
+const setFeedCacheValidators = `-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+SET
+  etag = $2,
+  last_modified = $3
 WHERE
   feeds.id = $1
 `
 
-func (q *Queries) MarkFeedFetched(ctx context.Context, id uuid.UUID) error {
-	_, err := q.db.ExecContext(ctx, markFeedFetched, id)
+type SetFeedCacheValidatorsParams struct {
+	ID           uuid.UUID
+	Etag         sql.NullString
+	LastModified sql.NullString
+}
+
+func (q *Queries) SetFeedCacheValidators(ctx context.Context, arg SetFeedCacheValidatorsParams) error {
+	_, err := q.db.ExecContext(ctx, setFeedCacheValidators, arg.ID, arg.Etag, arg.LastModified)
+	return err
+}
+
+const updateFeedMetadata = `-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET
+  site_url = $2,
+  description = $3,
+  icon_url = $4,
+  language = $5
+WHERE
+  feeds.id = $1
+`
+
+type UpdateFeedMetadataParams struct {
+	ID          uuid.UUID
+	SiteUrl     sql.NullString
+	Description sql.NullString
+	IconUrl     sql.NullString
+	Language    sql.NullString
+}
+
+func (q *Queries) UpdateFeedMetadata(ctx context.Context, arg UpdateFeedMetadataParams) error {
+	_, err := q.db.ExecContext(ctx, updateFeedMetadata,
+		arg.ID,
+		arg.SiteUrl,
+		arg.Description,
+		arg.IconUrl,
+		arg.Language,
+	)
 	return err
 }
@@ -12,13 +12,34 @@ import (
 )
 
 type Feed struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Name          string
-	Url           string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
+	ID                  uuid.UUID
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+	Name                string
+	Url                 string
+	UserID              uuid.UUID
+	LastFetchedAt       sql.NullTime
+	ClaimedBy           sql.NullString
+	LockedUntil         sql.NullTime
+	NextFetchAt         sql.NullTime
+	Etag                sql.NullString
+	LastModified        sql.NullString
+	LastError           sql.NullString
+	LastErrorAt         sql.NullTime
+	ConsecutiveFailures int32
+	LastSuccessAt       sql.NullTime
+	LastStatusCode      sql.NullInt32
+	PausedAt            sql.NullTime
+	SiteUrl             sql.NullString
+	Description         sql.NullString
+	IconUrl             sql.NullString
+	Language            sql.NullString
+}
+
+type FeedAlias struct {
+	Url       string
+	FeedID    uuid.UUID
+	CreatedAt time.Time
 }
 
 type FeedFollow struct {
@@ -27,17 +48,55 @@ type FeedFollow struct {
 	UpdatedAt time.Time
 	UserID    uuid.UUID
 	FeedID    uuid.UUID
+	Category  sql.NullString
+}
+
+type FeedHistory struct {
+	ID        uuid.UUID
+	CreatedAt time.Time
+	FeedID    uuid.UUID
+	Event     string
+	Detail    string
 }
 
 type Post struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     string
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Guid            string
+	ContentHash     string
+	SourceUpdatedAt sql.NullTime
+	SearchVector    interface{}
+}
+
+type PostRevision struct {
 	ID          uuid.UUID
 	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	PostID      uuid.UUID
 	Title       string
 	Url         string
 	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ContentHash string
+}
+
+type PostState struct {
+	UserID    uuid.UUID
+	PostID    uuid.UUID
+	Read      bool
+	ReadAt    sql.NullTime
+	StarredAt sql.NullTime
+	SavedAt   sql.NullTime
+}
+
+type PrunedPost struct {
+	FeedID   uuid.UUID
+	Guid     string
+	PrunedAt time.Time
 }
 
 type User struct {
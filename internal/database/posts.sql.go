@@ -13,6 +13,284 @@ import (
 	"github.com/google/uuid"
 )
 
+const browsePostsByFetched = `-- name: BrowsePostsByFetched :many
+SELECT
+  posts.id,
+  posts.created_at,
+  posts.updated_at,
+  posts.title,
+  posts.url,
+  posts.description,
+  posts.published_at,
+  posts.feed_id,
+  posts.guid,
+  posts.content_hash,
+  posts.source_updated_at,
+  feeds.name AS feed_name,
+  COALESCE(post_states.read, FALSE) AS read
+FROM
+  posts
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+  INNER JOIN feeds ON feeds.id = posts.feed_id
+  LEFT JOIN post_states ON post_states.post_id = posts.id
+  AND post_states.user_id = feed_follows.user_id
+WHERE
+  feed_follows.user_id = $1
+  AND (
+    NOT $2::boolean
+    OR post_states.read IS NOT TRUE
+  )
+  AND (
+    $3::uuid IS NULL
+    OR posts.feed_id = $3
+  )
+  AND (
+    $4::text IS NULL
+    OR feed_follows.category = $4
+    OR starts_with(feed_follows.category, $4 || '/')
+  )
+  AND (
+    $5::timestamp IS NULL
+    OR posts.created_at >= $5
+  )
+  AND (
+    $6::timestamp IS NULL
+    OR posts.created_at < $6
+  )
+  AND (
+    $7::timestamp IS NULL
+    OR (posts.created_at, posts.id) < (
+      $7::timestamp,
+      $8::uuid
+    )
+  )
+ORDER BY
+  posts.created_at DESC,
+  posts.id DESC
+LIMIT
+  $9
+OFFSET
+  $10
+`
+
+type BrowsePostsByFetchedParams struct {
+	UserID     uuid.UUID
+	UnreadOnly bool
+	FeedID     uuid.NullUUID
+	Category   sql.NullString
+	Since      sql.NullTime
+	Until      sql.NullTime
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	PostLimit  int32
+	PostOffset int32
+}
+
+type BrowsePostsByFetchedRow struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     string
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Guid            string
+	ContentHash     string
+	SourceUpdatedAt sql.NullTime
+	FeedName        string
+	Read            bool
+}
+
+// posts of the followed feeds, newest fetched first. the cursor is the
+// (created_at, id) of the last post of the previous page
+func (q *Queries) BrowsePostsByFetched(ctx context.Context, arg BrowsePostsByFetchedParams) ([]BrowsePostsByFetchedRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsByFetched,
+		arg.UserID,
+		arg.UnreadOnly,
+		arg.FeedID,
+		arg.Category,
+		arg.Since,
+		arg.Until,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PostLimit,
+		arg.PostOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsByFetchedRow
+	for rows.Next() {
+		var i BrowsePostsByFetchedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.SourceUpdatedAt,
+			&i.FeedName,
+			&i.Read,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const browsePostsByPublished = `-- name: BrowsePostsByPublished :many
+SELECT
+  posts.id,
+  posts.created_at,
+  posts.updated_at,
+  posts.title,
+  posts.url,
+  posts.description,
+  posts.published_at,
+  posts.feed_id,
+  posts.guid,
+  posts.content_hash,
+  posts.source_updated_at,
+  feeds.name AS feed_name,
+  COALESCE(post_states.read, FALSE) AS read
+FROM
+  posts
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+  INNER JOIN feeds ON feeds.id = posts.feed_id
+  LEFT JOIN post_states ON post_states.post_id = posts.id
+  AND post_states.user_id = feed_follows.user_id
+WHERE
+  feed_follows.user_id = $1
+  AND (
+    NOT $2::boolean
+    OR post_states.read IS NOT TRUE
+  )
+  AND (
+    $3::uuid IS NULL
+    OR posts.feed_id = $3
+  )
+  AND (
+    $4::text IS NULL
+    OR feed_follows.category = $4
+    OR starts_with(feed_follows.category, $4 || '/')
+  )
+  AND (
+    $5::timestamp IS NULL
+    OR posts.published_at >= $5
+  )
+  AND (
+    $6::timestamp IS NULL
+    OR posts.published_at < $6
+  )
+  AND (
+    $7::timestamp IS NULL
+    OR (posts.published_at, posts.id) < (
+      $7::timestamp,
+      $8::uuid
+    )
+  )
+ORDER BY
+  posts.published_at DESC,
+  posts.id DESC
+LIMIT
+  $9
+OFFSET
+  $10
+`
+
+type BrowsePostsByPublishedParams struct {
+	UserID     uuid.UUID
+	UnreadOnly bool
+	FeedID     uuid.NullUUID
+	Category   sql.NullString
+	Since      sql.NullTime
+	Until      sql.NullTime
+	CursorTime sql.NullTime
+	CursorID   uuid.NullUUID
+	PostLimit  int32
+	PostOffset int32
+}
+
+type BrowsePostsByPublishedRow struct {
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     string
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Guid            string
+	ContentHash     string
+	SourceUpdatedAt sql.NullTime
+	FeedName        string
+	Read            bool
+}
+
+// posts of the followed feeds, newest first. the cursor is the
+// (published_at, id) of the last post of the previous page
+func (q *Queries) BrowsePostsByPublished(ctx context.Context, arg BrowsePostsByPublishedParams) ([]BrowsePostsByPublishedRow, error) {
+	rows, err := q.db.QueryContext(ctx, browsePostsByPublished,
+		arg.UserID,
+		arg.UnreadOnly,
+		arg.FeedID,
+		arg.Category,
+		arg.Since,
+		arg.Until,
+		arg.CursorTime,
+		arg.CursorID,
+		arg.PostLimit,
+		arg.PostOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BrowsePostsByPublishedRow
+	for rows.Next() {
+		var i BrowsePostsByPublishedRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.PublishedAt,
+			&i.FeedID,
+			&i.Guid,
+			&i.ContentHash,
+			&i.SourceUpdatedAt,
+			&i.FeedName,
+			&i.Read,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const createPost = `-- name: CreatePost :one
 INSERT INTO
   posts (
@@ -23,24 +301,30 @@ INSERT INTO
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    guid,
+    content_hash,
+    source_updated_at
   )
 VALUES
-  ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at, updated_at, title, url, description, published_at, feed_id
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id
 `
 
 type CreatePostParams struct {
-	ID          uuid.UUID
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
-	Title       string
-	Url         string
-	Description string
-	PublishedAt time.Time
-	FeedID      uuid.UUID
+	ID              uuid.UUID
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	Title           string
+	Url             string
+	Description     string
+	PublishedAt     time.Time
+	FeedID          uuid.UUID
+	Guid            string
+	ContentHash     string
+	SourceUpdatedAt sql.NullTime
 }
 
-func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, error) {
+func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (uuid.UUID, error) {
 	row := q.db.QueryRowContext(ctx, createPost,
 		arg.ID,
 		arg.CreatedAt,
@@ -50,84 +334,229 @@ func (q *Queries) CreatePost(ctx context.Context, arg CreatePostParams) (Post, e
 		arg.Description,
 		arg.PublishedAt,
 		arg.FeedID,
+		arg.Guid,
+		arg.ContentHash,
+		arg.SourceUpdatedAt,
 	)
-	var i Post
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
+
+const getPostByFeedAndGuid = `-- name: GetPostByFeedAndGuid :one
+SELECT
+  id,
+  title,
+  description,
+  content_hash,
+  source_updated_at
+FROM
+  posts
+WHERE
+  feed_id = $1
+  AND guid = $2
+`
+
+type GetPostByFeedAndGuidParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+type GetPostByFeedAndGuidRow struct {
+	ID              uuid.UUID
+	Title           string
+	Description     string
+	ContentHash     string
+	SourceUpdatedAt sql.NullTime
+}
+
+// what savePost needs to tell whether an item changed
+func (q *Queries) GetPostByFeedAndGuid(ctx context.Context, arg GetPostByFeedAndGuidParams) (GetPostByFeedAndGuidRow, error) {
+	row := q.db.QueryRowContext(ctx, getPostByFeedAndGuid, arg.FeedID, arg.Guid)
+	var i GetPostByFeedAndGuidRow
 	err := row.Scan(
 		&i.ID,
-		&i.CreatedAt,
-		&i.UpdatedAt,
 		&i.Title,
-		&i.Url,
 		&i.Description,
-		&i.PublishedAt,
-		&i.FeedID,
+		&i.ContentHash,
+		&i.SourceUpdatedAt,
 	)
 	return i, err
 }
 
-const getPostsByUser = `-- name: GetPostsByUser :many
+const getPostRevisions = `-- name: GetPostRevisions :many
 SELECT
-  posts.id, posts.created_at, posts.updated_at, posts.title, posts.url, posts.description, posts.published_at, posts.feed_id,
-  feeds.id, feeds.created_at, feeds.updated_at, feeds.name, feeds.url, feeds.user_id, feeds.last_fetched_at
+  id, created_at, post_id, title, url, description, content_hash
+FROM
+  post_revisions
+WHERE
+  post_id = $1
+ORDER BY
+  created_at DESC
+`
+
+func (q *Queries) GetPostRevisions(ctx context.Context, postID uuid.UUID) ([]PostRevision, error) {
+	rows, err := q.db.QueryContext(ctx, getPostRevisions, postID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PostRevision
+	for rows.Next() {
+		var i PostRevision
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.PostID,
+			&i.Title,
+			&i.Url,
+			&i.Description,
+			&i.ContentHash,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const isPostPruned = `-- name: IsPostPruned :one
+SELECT
+  EXISTS (
+    SELECT
+      1
+    FROM
+      pruned_posts
+    WHERE
+      pruned_posts.feed_id = $1
+      AND pruned_posts.guid = $2
+  )
+`
+
+type IsPostPrunedParams struct {
+	FeedID uuid.UUID
+	Guid   string
+}
+
+func (q *Queries) IsPostPruned(ctx context.Context, arg IsPostPrunedParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, isPostPruned, arg.FeedID, arg.Guid)
+	var exists bool
+	err := row.Scan(&exists)
+	return exists, err
+}
+
+const prunePosts = `-- name: PrunePosts :execrows
+WITH
+  pruned AS (
+    DELETE FROM posts
+    WHERE
+      posts.published_at < $1
+      AND NOT EXISTS (
+        SELECT
+          1
+        FROM
+          post_states
+        WHERE
+          post_states.post_id = posts.id
+          AND (
+            post_states.starred_at IS NOT NULL
+            OR post_states.saved_at IS NOT NULL
+          )
+      ) RETURNING posts.feed_id,
+      posts.guid
+  )
+INSERT INTO
+  pruned_posts (feed_id, guid, pruned_at)
+SELECT
+  pruned.feed_id,
+  pruned.guid,
+  Now()
+FROM
+  pruned
+ON CONFLICT (feed_id, guid) DO NOTHING
+`
+
+// posts starred or saved by anyone are kept whatever their age, the guids of
+// the others are kept so the next fetch doesn't store them again
+func (q *Queries) PrunePosts(ctx context.Context, publishedBefore time.Time) (int64, error) {
+	result, err := q.db.ExecContext(ctx, prunePosts, publishedBefore)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const searchPosts = `-- name: SearchPosts :many
+SELECT
+  posts.id,
+  posts.title,
+  posts.url,
+  posts.published_at,
+  feeds.name AS feed_name,
+  ts_rank(
+    posts.search_vector,
+    to_tsquery('english', $1::text)
+  ) AS rank,
+  ts_headline(
+    'english',
+    regexp_replace(posts.description, '<[^>]*>', ' ', 'g'),
+    to_tsquery('english', $1::text),
+    'StartSel=**, StopSel=**, MaxFragments=2, MinWords=5, MaxWords=20'
+  ) AS snippet
 FROM
   posts
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
   INNER JOIN feeds ON feeds.id = posts.feed_id
 WHERE
-  feeds.user_id = $1
+  feed_follows.user_id = $2
+  AND posts.search_vector @@ to_tsquery('english', $1::text)
 ORDER BY
+  rank DESC,
   posts.published_at DESC
 LIMIT
-  $2
+  $3
 `
 
-type GetPostsByUserParams struct {
-	UserID uuid.UUID
-	Limit  int32
-}
-
-type GetPostsByUserRow struct {
-	ID            uuid.UUID
-	CreatedAt     time.Time
-	UpdatedAt     time.Time
-	Title         string
-	Url           string
-	Description   string
-	PublishedAt   time.Time
-	FeedID        uuid.UUID
-	ID_2          uuid.UUID
-	CreatedAt_2   time.Time
-	UpdatedAt_2   time.Time
-	Name          string
-	Url_2         string
-	UserID        uuid.UUID
-	LastFetchedAt sql.NullTime
-}
-
-func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams) ([]GetPostsByUserRow, error) {
-	rows, err := q.db.QueryContext(ctx, getPostsByUser, arg.UserID, arg.Limit)
+type SearchPostsParams struct {
+	Query     string
+	UserID    uuid.UUID
+	PostLimit int32
+}
+
+type SearchPostsRow struct {
+	ID          uuid.UUID
+	Title       string
+	Url         string
+	PublishedAt time.Time
+	FeedName    string
+	Rank        float32
+	Snippet     string
+}
+
+// query is a to_tsquery expression, searched in the followed feeds only
+func (q *Queries) SearchPosts(ctx context.Context, arg SearchPostsParams) ([]SearchPostsRow, error) {
+	rows, err := q.db.QueryContext(ctx, searchPosts, arg.Query, arg.UserID, arg.PostLimit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	var items []GetPostsByUserRow
+	var items []SearchPostsRow
 	for rows.Next() {
-		var i GetPostsByUserRow
+		var i SearchPostsRow
 		if err := rows.Scan(
 			&i.ID,
-			&i.CreatedAt,
-			&i.UpdatedAt,
 			&i.Title,
 			&i.Url,
-			&i.Description,
 			&i.PublishedAt,
-			&i.FeedID,
-			&i.ID_2,
-			&i.CreatedAt_2,
-			&i.UpdatedAt_2,
-			&i.Name,
-			&i.Url_2,
-			&i.UserID,
-			&i.LastFetchedAt,
+			&i.FeedName,
+			&i.Rank,
+			&i.Snippet,
 		); err != nil {
 			return nil, err
 		}
@@ -141,3 +570,67 @@ func (q *Queries) GetPostsByUser(ctx context.Context, arg GetPostsByUserParams)
 	}
 	return items, nil
 }
+
+const updatePostContent = `-- name: UpdatePostContent :one
+WITH
+  revision AS (
+    INSERT INTO
+      post_revisions (
+        id,
+        created_at,
+        post_id,
+        title,
+        url,
+        description,
+        content_hash
+      )
+    SELECT
+      $1::uuid,
+      Now(),
+      posts.id,
+      posts.title,
+      posts.url,
+      posts.description,
+      posts.content_hash
+    FROM
+      posts
+    WHERE
+      posts.id = $2
+  )
+UPDATE posts
+SET
+  updated_at = Now(),
+  title = $3,
+  url = $4,
+  description = $5,
+  content_hash = $6,
+  source_updated_at = $7
+WHERE
+  posts.id = $2 RETURNING posts.id
+`
+
+type UpdatePostContentParams struct {
+	RevisionID      uuid.UUID
+	PostID          uuid.UUID
+	Title           string
+	Url             string
+	Description     string
+	ContentHash     string
+	SourceUpdatedAt sql.NullTime
+}
+
+// the previous version of the post is kept in post_revisions
+func (q *Queries) UpdatePostContent(ctx context.Context, arg UpdatePostContentParams) (uuid.UUID, error) {
+	row := q.db.QueryRowContext(ctx, updatePostContent,
+		arg.RevisionID,
+		arg.PostID,
+		arg.Title,
+		arg.Url,
+		arg.Description,
+		arg.ContentHash,
+		arg.SourceUpdatedAt,
+	)
+	var id uuid.UUID
+	err := row.Scan(&id)
+	return id, err
+}
@@ -0,0 +1,172 @@
+// Package opml reads and writes OPML subscription lists, the format feed
+// readers use to import and export what a user follows.
+package opml
+
+import (
+	"cmp"
+	"encoding/xml"
+	"io"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+type OPML struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    Head     `xml:"head"`
+	Body    Body     `xml:"body"`
+}
+
+type Head struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type Body struct {
+	Outlines []Outline `xml:"outline"`
+}
+
+// Outline is either a feed, when it has an XMLURL, or a folder holding
+// other outlines.
+type Outline struct {
+	Text     string    `xml:"text,attr"`
+	Title    string    `xml:"title,attr,omitempty"`
+	Type     string    `xml:"type,attr,omitempty"`
+	XMLURL   string    `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string    `xml:"htmlUrl,attr,omitempty"`
+	Outlines []Outline `xml:"outline"`
+}
+
+// UnmarshalXML reads the attributes whatever their case, exporters don't
+// agree on xmlUrl vs xmlURL vs xmlurl.
+func (o *Outline) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, attr := range start.Attr {
+		value := strings.TrimSpace(attr.Value)
+		switch strings.ToLower(attr.Name.Local) {
+		case "text":
+			o.Text = value
+		case "title":
+			o.Title = value
+		case "type":
+			o.Type = value
+		case "xmlurl":
+			o.XMLURL = value
+		case "htmlurl":
+			o.HTMLURL = value
+		}
+	}
+
+	var children struct {
+		Outlines []Outline `xml:"outline"`
+	}
+	if err := d.DecodeElement(&children, &start); err != nil {
+		return err
+	}
+	o.Outlines = children.Outlines
+	return nil
+}
+
+// Feed is a subscription listed in an OPML document.
+type Feed struct {
+	Title   string
+	URL     string
+	SiteURL string
+	// Category is the path of the folders holding the feed, like "tech/go",
+	// empty for feeds at the top level
+	Category string
+}
+
+// Parse reads an OPML 1.0 or 2.0 document.
+func Parse(r io.Reader) (*OPML, error) {
+	decoder := xml.NewDecoder(r)
+	decoder.CharsetReader = charset.NewReaderLabel
+	var doc OPML
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// Feeds lists the feeds of the document, nested folders included.
+func (o *OPML) Feeds() []Feed {
+	return collectFeeds(nil, o.Body.Outlines, nil)
+}
+
+func collectFeeds(feeds []Feed, outlines []Outline, folders []string) []Feed {
+	for _, outline := range outlines {
+		if outline.XMLURL != "" {
+			feeds = append(feeds, Feed{
+				Title:    cmp.Or(outline.Title, outline.Text),
+				URL:      outline.XMLURL,
+				SiteURL:  outline.HTMLURL,
+				Category: strings.Join(folders, "/"),
+			})
+			feeds = collectFeeds(feeds, outline.Outlines, folders)
+			continue
+		}
+
+		folder := cmp.Or(outline.Text, outline.Title)
+		if folder == "" {
+			feeds = collectFeeds(feeds, outline.Outlines, folders)
+			continue
+		}
+		feeds = collectFeeds(feeds, outline.Outlines, append(folders[:len(folders):len(folders)], folder))
+	}
+	return feeds
+}
+
+// New builds an OPML 2.0 document listing feeds, grouped in folders after
+// their categories.
+func New(title string, created time.Time, feeds []Feed) *OPML {
+	doc := &OPML{
+		Version: "2.0",
+		Head: Head{
+			Title:       title,
+			DateCreated: created.Format(time.RFC1123Z),
+		},
+	}
+	for _, feed := range feeds {
+		outlines := &doc.Body.Outlines
+		for _, folder := range strings.Split(feed.Category, "/") {
+			if folder = strings.TrimSpace(folder); folder != "" {
+				outlines = &folderOutline(outlines, folder).Outlines
+			}
+		}
+		*outlines = append(*outlines, Outline{
+			Text:    feed.Title,
+			Title:   feed.Title,
+			Type:    "rss",
+			XMLURL:  feed.URL,
+			HTMLURL: feed.SiteURL,
+		})
+	}
+	return doc
+}
+
+// folderOutline returns the folder named name among outlines, adding it
+// when missing.
+func folderOutline(outlines *[]Outline, name string) *Outline {
+	for i := range *outlines {
+		if (*outlines)[i].XMLURL == "" && (*outlines)[i].Text == name {
+			return &(*outlines)[i]
+		}
+	}
+	*outlines = append(*outlines, Outline{Text: name, Title: name})
+	return &(*outlines)[len(*outlines)-1]
+}
+
+// Write encodes doc to w, XML declaration included.
+func Write(w io.Writer, doc *OPML) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
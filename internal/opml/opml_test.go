@@ -0,0 +1,71 @@
+package opml
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFeeds(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<opml version="1.0">
+  <head><title>Subscriptions</title></head>
+  <body>
+    <outline text="Top" xmlUrl="https://top.example/feed" htmlUrl="https://top.example/"/>
+    <outline text="Tech">
+      <outline text="Go">
+        <outline text="Go Blog" title="The Go Blog" type="rss" xmlURL=" https://go.dev/blog/feed.atom "/>
+      </outline>
+      <outline title="Untitled folder feed" XMLURL="https://tech.example/rss"/>
+    </outline>
+    <outline>
+      <outline text="Unnamed folder" xmlurl="https://loose.example/rss"/>
+    </outline>
+  </body>
+</opml>`
+
+	parsed, err := Parse(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+	want := []Feed{
+		{Title: "Top", URL: "https://top.example/feed", SiteURL: "https://top.example/"},
+		{Title: "The Go Blog", URL: "https://go.dev/blog/feed.atom", Category: "Tech/Go"},
+		{Title: "Untitled folder feed", URL: "https://tech.example/rss", Category: "Tech"},
+		{Title: "Unnamed folder", URL: "https://loose.example/rss"},
+	}
+	if got := parsed.Feeds(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Feeds() = %+v, want %+v", got, want)
+	}
+}
+
+func TestWriteRoundTrip(t *testing.T) {
+	feeds := []Feed{
+		{Title: "Top", URL: "https://top.example/feed", SiteURL: "https://top.example/"},
+		{Title: "Go Blog", URL: "https://go.dev/blog/feed.atom", Category: "tech/go"},
+		{Title: "Tech & more", URL: "https://tech.example/rss?a=1&b=2", Category: "tech"},
+		{Title: "Rust Blog", URL: "https://blog.rust-lang.org/feed.xml", Category: "tech/rust"},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, New("gator", time.Now(), feeds)); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+	parsed, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse returned error: %v", err)
+	}
+
+	if got := parsed.Feeds(); !reflect.DeepEqual(got, feeds) {
+		t.Errorf("Feeds() = %+v, want %+v", got, feeds)
+	}
+	// tech/go and tech/rust share the tech folder
+	if len(parsed.Body.Outlines) != 2 {
+		t.Errorf("got %d top-level outlines, want 2", len(parsed.Body.Outlines))
+	}
+	if parsed.Head.Title != "gator" || parsed.Version != "2.0" {
+		t.Errorf("head = %+v, version %q", parsed.Head, parsed.Version)
+	}
+}
@@ -0,0 +1,103 @@
+package rss
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"feed"`
+	Lang     string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Title    atomText    `xml:"title"`
+	Subtitle atomText    `xml:"subtitle"`
+	Links    []atomLink  `xml:"link"`
+	Icon     string      `xml:"icon"`
+	Logo     string      `xml:"logo"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     atomText   `xml:"title"`
+	Links     []atomLink `xml:"link"`
+	Summary   atomText   `xml:"summary"`
+	Content   atomText   `xml:"content"`
+	Published string     `xml:"published"`
+	Updated   string     `xml:"updated"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+// atomText covers the three Atom text constructs: "text" and "html" carry
+// their value as character data, "xhtml" wraps it in a div we keep as markup.
+type atomText struct {
+	Type  string `xml:"type,attr"`
+	Text  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+func (t atomText) String() string {
+	if t.Type == "xhtml" {
+		return strings.TrimSpace(t.Inner)
+	}
+	return strings.TrimSpace(t.Text)
+}
+
+// alternateLink returns the href of the rel="alternate" link (a link without
+// rel is an alternate per RFC 4287), preferring an HTML one when several exist.
+func alternateLink(links []atomLink) string {
+	var href string
+	for _, link := range links {
+		if link.Rel != "" && link.Rel != "alternate" {
+			continue
+		}
+		if link.Type == "" || link.Type == "text/html" {
+			return link.Href
+		}
+		if href == "" {
+			href = link.Href
+		}
+	}
+	return href
+}
+
+func (f *atomFeed) toRSS() *RSSFeed {
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = f.Title.String()
+	rssFeed.Channel.Link = alternateLink(f.Links)
+	rssFeed.Channel.Description = f.Subtitle.String()
+	rssFeed.Channel.Language = f.Lang
+	rssFeed.Channel.Image.URL = f.Icon
+	if rssFeed.Channel.Image.URL == "" {
+		rssFeed.Channel.Image.URL = f.Logo
+	}
+
+	for _, entry := range f.Entries {
+		// summary is the short form, but plenty of feeds only ship content
+		description := entry.Summary.String()
+		if description == "" {
+			description = entry.Content.String()
+		}
+
+		pubDate := entry.Published
+		if pubDate == "" {
+			pubDate = entry.Updated
+		}
+
+		// atom ids are often tag: URIs, never treat them as links
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			Title:       entry.Title.String(),
+			Link:        alternateLink(entry.Links),
+			Description: description,
+			PubDate:     pubDate,
+			GUID:        RSSGUID{Value: entry.ID, IsPermaLink: "false"},
+			Updated:     entry.Updated,
+		})
+	}
+
+	return &rssFeed
+}
@@ -0,0 +1,83 @@
+package rss
+
+import "testing"
+
+func TestParseAtomFeed(t *testing.T) {
+	tests := []struct {
+		name  string
+		doc   string
+		title string
+		link  string
+		item  RSSItem
+	}{
+		{
+			name: "alternate link and summary",
+			doc: `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example</title>
+  <link rel="self" href="https://example.com/atom.xml"/>
+  <link href="https://example.com/"/>
+  <entry>
+    <id>tag:example.com,2006:1</id>
+    <title>First</title>
+    <link rel="alternate" type="text/html" href="https://example.com/first"/>
+    <summary>Short</summary>
+    <content type="html">Long</content>
+    <published>2006-01-02T15:04:05Z</published>
+    <updated>2006-01-03T15:04:05Z</updated>
+  </entry>
+</feed>`,
+			title: "Example",
+			link:  "https://example.com/",
+			item: RSSItem{
+				Title:       "First",
+				Link:        "https://example.com/first",
+				Description: "Short",
+				PubDate:     "2006-01-02T15:04:05Z",
+				GUID:        RSSGUID{Value: "tag:example.com,2006:1", IsPermaLink: "false"},
+				Updated:     "2006-01-03T15:04:05Z",
+			},
+		},
+		{
+			name: "content only, updated only, xhtml",
+			doc: `<feed xmlns="http://www.w3.org/2005/Atom">
+  <title type="text"> Example </title>
+  <entry>
+    <id>urn:uuid:1</id>
+    <title>Second</title>
+    <link rel="enclosure" href="https://example.com/audio.mp3"/>
+    <link href="/second"/>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml">Body</div></content>
+    <updated>2006-01-02T15:04:05Z</updated>
+  </entry>
+</feed>`,
+			title: "Example",
+			link:  "",
+			item: RSSItem{
+				Title:       "Second",
+				Link:        "/second",
+				Description: `<div xmlns="http://www.w3.org/1999/xhtml">Body</div>`,
+				PubDate:     "2006-01-02T15:04:05Z",
+				GUID:        RSSGUID{Value: "urn:uuid:1", IsPermaLink: "false"},
+				Updated:     "2006-01-02T15:04:05Z",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.doc), "application/atom+xml")
+			if err != nil {
+				t.Fatalf("parseFeed returned error: %v", err)
+			}
+			if feed.Channel.Title != tt.title || feed.Channel.Link != tt.link {
+				t.Errorf("channel = %q %q, want %q %q", feed.Channel.Title, feed.Channel.Link, tt.title, tt.link)
+			}
+			if len(feed.Channel.Item) != 1 {
+				t.Fatalf("got %d items, want 1", len(feed.Channel.Item))
+			}
+			if got := feed.Channel.Item[0]; got != tt.item {
+				t.Errorf("item = %+v, want %+v", got, tt.item)
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html/charset"
+)
+
+// decodeCharset converts data to UTF-8 when its encoding is known before
+// parsing, from a UTF-16 byte order mark or the charset of the Content-Type
+// header. the header wins over the XML prolog, settled reports whether the
+// encoding the prolog declares has to be ignored afterwards.
+func decodeCharset(data []byte, contentType string) (decoded []byte, settled bool, err error) {
+	var label string
+	switch {
+	case bytes.HasPrefix(data, []byte("\xfe\xff")):
+		label, data = "utf-16be", data[2:]
+	case bytes.HasPrefix(data, []byte("\xff\xfe")):
+		label, data = "utf-16le", data[2:]
+	default:
+		if _, params, err := mime.ParseMediaType(contentType); err == nil {
+			label = strings.TrimSpace(params["charset"])
+		}
+	}
+
+	if label == "" {
+		return data, false, nil
+	}
+	if isUTF8Label(label) {
+		// plenty of servers tack charset=utf-8 on everything, when the body
+		// says otherwise let the prolog have a go
+		return data, utf8.Valid(data), nil
+	}
+
+	reader, err := charset.NewReaderLabel(label, bytes.NewReader(data))
+	if err != nil {
+		return nil, false, err
+	}
+	decoded, err = io.ReadAll(reader)
+	if err != nil {
+		return nil, false, err
+	}
+	return decoded, true, nil
+}
+
+func isUTF8Label(label string) bool {
+	return strings.EqualFold(label, "utf-8") || strings.EqualFold(label, "utf8")
+}
+
+// newXMLDecoder returns a decoder that understands the encodings declared
+// in XML prologs (ISO-8859-1, Windows-1252, Shift_JIS...), unless the data
+// was already converted to UTF-8 by decodeCharset.
+func newXMLDecoder(data []byte, settled bool) *xml.Decoder {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.CharsetReader = charset.NewReaderLabel
+	if settled {
+		decoder.CharsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	}
+	return decoder
+}
@@ -0,0 +1,46 @@
+package rss
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+func TestParseFeedCharsets(t *testing.T) {
+	utf16LE := []byte{0xff, 0xfe}
+	for _, unit := range utf16.Encode([]rune(`<?xml version="1.0" encoding="UTF-16"?><rss><channel><title>Café</title></channel></rss>`)) {
+		utf16LE = binary.LittleEndian.AppendUint16(utf16LE, unit)
+	}
+
+	tests := []struct {
+		name        string
+		doc         []byte
+		contentType string
+		want        string
+	}{
+		{"utf-8", []byte(`<rss><channel><title>Café</title></channel></rss>`), "application/rss+xml", "Café"},
+		{"utf-8 with BOM", []byte("\xef\xbb\xbf<rss><channel><title>Café</title></channel></rss>"), "text/xml; charset=utf-8", "Café"},
+		{"latin-1 prolog", []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss><channel><title>Caf\xe9</title></channel></rss>"), "text/xml", "Café"},
+		{"windows-1252 header", []byte("<rss><channel><title>\x93Caf\xe9\x94</title></channel></rss>"), "text/xml; charset=windows-1252", "“Café”"},
+		{"header wins over prolog", []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss><channel><title>\x83J\x83t\x83F</title></channel></rss>"), "text/xml; charset=Shift_JIS", "カフェ"},
+		{"utf-8 header on latin-1 body", []byte("<?xml version=\"1.0\" encoding=\"ISO-8859-1\"?><rss><channel><title>Caf\xe9</title></channel></rss>"), "text/xml; charset=UTF-8", "Café"},
+		{"utf-16 BOM", utf16LE, "application/xml", "Café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed(tt.doc, tt.contentType)
+			if err != nil {
+				t.Fatalf("parseFeed returned error: %v", err)
+			}
+			if feed.Channel.Title != tt.want {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCharsetUnknownLabel(t *testing.T) {
+	if _, _, err := decodeCharset([]byte("<rss/>"), "text/xml; charset=x-unknown"); err == nil {
+		t.Error("decodeCharset accepted an unknown charset")
+	}
+}
@@ -1,29 +1,146 @@
 package rss
 
 import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
 	"context"
-	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
+	"log/slog"
+	"mime"
+	"net"
 	"net/http"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+const (
+	DefaultTimeout     = 30 * time.Second
+	DefaultMaxBodySize = 10 << 20
+	dialTimeout        = 10 * time.Second
+	maxRedirects       = 10
 )
 
-type RSSFeed struct {
-	Channel struct {
-		Title       string    `xml:"title"`
-		Link        string    `xml:"link"`
-		Description string    `xml:"description"`
-		Item        []RSSItem `xml:"item"`
-	} `xml:"channel"`
+var ErrBodyTooLarge = errors.New("response body too large")
+
+// ErrBodyEncoding is returned when the body can't be decoded according to
+// its Content-Encoding, the server sent something broken or unsupported.
+var ErrBodyEncoding = errors.New("bad content encoding")
+
+// NotFeedError is returned (wrapped in a FetchError) when a URL serves
+// something that is not a feed, typically the HTML page of a website.
+type NotFeedError struct {
+	URL         string
+	ContentType string
+	// Body is kept so the page can be searched for the feeds it links to
+	Body []byte
+}
+
+func (e *NotFeedError) Error() string {
+	return fmt.Sprintf("%s is not a feed (content type %s)", e.URL, e.ContentType)
+}
+
+// Client fetches feeds. unlike http.DefaultClient it gives up on servers
+// that hang and on bodies bigger than MaxBodySize, and it does its own
+// content decoding so brotli is supported too.
+type Client struct {
+	httpClient  *http.Client
+	maxBodySize int64
+}
+
+var DefaultClient = NewClient(DefaultTimeout, DefaultMaxBodySize)
+
+// NewClient returns a Client whose requests, body included, give up after
+// timeout and whose responses are capped to maxBodySize decoded bytes.
+func NewClient(timeout time.Duration, maxBodySize int64) *Client {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   dialTimeout,
+		ResponseHeaderTimeout: timeout,
+		IdleConnTimeout:       90 * time.Second,
+		MaxIdleConns:          100,
+		ForceAttemptHTTP2:     true,
+		// we ask for gzip/deflate/br ourselves, see decodeBody
+		DisableCompression: true,
+	}
+
+	return &Client{
+		httpClient: &http.Client{
+			Transport:     transport,
+			Timeout:       timeout,
+			CheckRedirect: checkRedirect,
+		},
+		maxBodySize: maxBodySize,
+	}
+}
+
+// FetchFeed fetches feedURL with DefaultClient.
+func FetchFeed(ctx context.Context, feedURL string, opts FetchOptions) (*FetchResult, error) {
+	return DefaultClient.FetchFeed(ctx, feedURL, opts)
 }
 
-type RSSItem struct {
-	Title       string `xml:"title"`
-	Link        string `xml:"link"`
-	Description string `xml:"description"`
-	PubDate     string `xml:"pubDate"`
+// FetchOptions carries the cache validators of the previous fetch, when
+// they are set the request is conditional.
+type FetchOptions struct {
+	ETag         string
+	LastModified string
+	// how many times a temporary failure is retried before giving up
+	Retries int
 }
 
-func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
+type FetchResult struct {
+	// Feed is nil when NotModified is set
+	Feed        *RSSFeed
+	NotModified bool
+	StatusCode  int
+	// PermanentURL is where the feed now lives when every redirect we
+	// followed was permanent (301/308), empty otherwise
+	PermanentURL string
+	// validators to send with the next request
+	ETag         string
+	LastModified string
+}
+
+// FetchFeed downloads and parses the feed at feedURL, retrying temporary
+// failures up to opts.Retries times. errors other than a bad URL are
+// returned as *FetchError.
+func (c *Client) FetchFeed(ctx context.Context, feedURL string, opts FetchOptions) (*FetchResult, error) {
+	for retry := 0; ; retry++ {
+		result, err := c.fetchOnce(ctx, feedURL, opts)
+		var fetchErr *FetchError
+		if err == nil || !errors.As(err, &fetchErr) || !fetchErr.Temporary() || retry >= opts.Retries {
+			return result, err
+		}
+
+		delay, ok := retryDelay(fetchErr, retry)
+		if !ok {
+			return nil, err
+		}
+		slog.Debug("retrying feed fetch", "url", feedURL, "delay", delay, "err", err)
+
+		select {
+		case <-ctx.Done():
+			return nil, requestError(ctx.Err())
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *Client) fetchOnce(ctx context.Context, feedURL string, opts FetchOptions) (*FetchResult, error) {
+	trace := &redirectTrace{permanent: true}
+	ctx = context.WithValue(ctx, redirectTraceKey{}, trace)
+
 	// body is nil, because we don't need to send something with the request
 	// and that's usually the case with GET requests.
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedURL, nil)
@@ -32,21 +149,192 @@ func FetchFeed(ctx context.Context, feedURL string) (*RSSFeed, error) {
 	}
 
 	req.Header.Set("User-Agent", "gator")
-	res, err := http.DefaultClient.Do(req)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/feed+json, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.8")
+	req.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	if opts.ETag != "" {
+		req.Header.Set("If-None-Match", opts.ETag)
+	}
+	if opts.LastModified != "" {
+		req.Header.Set("If-Modified-Since", opts.LastModified)
+	}
+	res, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, requestError(err)
 	}
 	defer res.Body.Close()
 
-	data, err := io.ReadAll(res.Body)
+	// a 304 may omit the validators, the ones we sent are still good then
+	result := &FetchResult{
+		ETag:         cmp.Or(res.Header.Get("ETag"), opts.ETag),
+		LastModified: cmp.Or(res.Header.Get("Last-Modified"), opts.LastModified),
+		StatusCode:   res.StatusCode,
+	}
+	if trace.redirects > 0 && trace.permanent {
+		result.PermanentURL = res.Request.URL.String()
+	}
+	if res.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		return result, nil
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, statusError(res)
+	}
+
+	data, err := c.readBody(res)
+	if errors.Is(err, ErrBodyTooLarge) {
+		return nil, &FetchError{Kind: ErrorTooLarge, StatusCode: res.StatusCode, Err: err}
+	}
+	if errors.Is(err, ErrBodyEncoding) {
+		return nil, &FetchError{Kind: ErrorEncoding, StatusCode: res.StatusCode, Err: err}
+	}
 	if err != nil {
-		return nil, err
+		return nil, requestError(err)
 	}
 
-	var rssFeed RSSFeed
-	if err := xml.Unmarshal(data, &rssFeed); err != nil {
-		return nil, err
+	contentType := res.Header.Get("Content-Type")
+	if !isFeedContent(data, contentType) {
+		return nil, &FetchError{
+			Kind:       ErrorNotFeed,
+			StatusCode: res.StatusCode,
+			Err:        &NotFeedError{URL: res.Request.URL.String(), ContentType: contentType, Body: data},
+		}
+	}
+
+	result.Feed, err = parseFeed(data, contentType)
+	if err != nil {
+		return nil, &FetchError{Kind: ErrorParse, StatusCode: res.StatusCode, Err: err}
+	}
+	return result, nil
+}
+
+// redirectTrace records the redirects followed by one request, it travels
+// in the request context since the http.Client is shared.
+type redirectTrace struct {
+	redirects int
+	// only a chain made of permanent redirects means the feed moved, a
+	// single temporary hop means the original url is still the one to use
+	permanent bool
+}
+
+type redirectTraceKey struct{}
+
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	if trace, ok := req.Context().Value(redirectTraceKey{}).(*redirectTrace); ok {
+		status := req.Response.StatusCode
+		trace.redirects++
+		trace.permanent = trace.permanent && (status == http.StatusMovedPermanently || status == http.StatusPermanentRedirect)
+	}
+	return nil
+}
+
+// readBody decodes the body according to its Content-Encoding and reads at
+// most maxBodySize bytes of it. the limit applies to the decoded content so
+// a small compressed response can't expand into something huge.
+func (c *Client) readBody(res *http.Response) ([]byte, error) {
+	// the decoders fail both on the connection breaking and on the bytes
+	// being corrupt, only the latter is the server's fault for good
+	raw := &errorRecorder{reader: res.Body}
+	body, err := decodeBody(raw, res.Header.Get("Content-Encoding"))
+	if err != nil {
+		return nil, bodyError(raw, err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(body, c.maxBodySize+1))
+	if err != nil {
+		return nil, bodyError(raw, err)
 	}
+	if int64(len(data)) > c.maxBodySize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, c.maxBodySize)
+	}
+	return data, nil
+}
 
-	return &rssFeed, nil
+// errorRecorder keeps the last error of the reader it wraps, other than EOF.
+type errorRecorder struct {
+	reader io.Reader
+	err    error
+}
+
+func (r *errorRecorder) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		r.err = err
+	}
+	return n, err
+}
+
+// bodyError tells a failure reading the response apart from one decoding
+// it, errors of the latter kind wrap ErrBodyEncoding.
+func bodyError(raw *errorRecorder, err error) error {
+	if raw.err != nil {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrBodyEncoding, err)
+}
+
+func decodeBody(body io.Reader, contentEncoding string) (io.ReadCloser, error) {
+	switch strings.ToLower(strings.TrimSpace(contentEncoding)) {
+	case "", "identity":
+		return io.NopCloser(body), nil
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "deflate":
+		// "deflate" is meant to be zlib wrapped, some servers send raw deflate
+		buffered := bufio.NewReader(body)
+		header, err := buffered.Peek(2)
+		if err == nil && zlibHeader(header) {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	case "br":
+		return io.NopCloser(brotli.NewReader(body)), nil
+	default:
+		return nil, fmt.Errorf("unsupported content encoding %q", contentEncoding)
+	}
+}
+
+func zlibHeader(header []byte) bool {
+	return header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0
+}
+
+// media types that can't hold a feed, whatever the url looks like
+var notFeedTypes = []string{"text/html", "image/", "audio/", "video/", "font/", "application/pdf", "application/zip"}
+
+// isFeedContent sniffs the body rather than trusting Content-Type alone:
+// plenty of servers send feeds as text/html or application/octet-stream.
+func isFeedContent(data []byte, contentType string) bool {
+	// http.DetectContentType takes any document starting with a comment for
+	// HTML, a feed without prolog may well start with a generator comment
+	if isFeedDocument(data) {
+		return true
+	}
+	if isNotFeedType(http.DetectContentType(data)) {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType == "text/html" {
+		// the body was sniffed as something else than HTML already
+		return true
+	}
+	return !isNotFeedType(mediaType)
+}
+
+// isFeedDocument tells whether data is XML with the root element of one of
+// the formats parseFeed reads.
+func isFeedDocument(data []byte) bool {
+	root, err := rootElement(newXMLDecoder(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), false))
+	return err == nil && (root == "rss" || root == "feed" || root == "rdf")
+}
+
+func isNotFeedType(mediaType string) bool {
+	for _, prefix := range notFeedTypes {
+		if strings.HasPrefix(mediaType, prefix) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,171 @@
+package rss
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+const testFeed = `<?xml version="1.0"?><rss version="2.0"><channel><title>Example</title></channel></rss>`
+
+func TestIsFeedContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        string
+		contentType string
+		want        bool
+	}{
+		{"rss", `<?xml version="1.0"?><rss version="2.0"><channel></channel></rss>`, "application/rss+xml", true},
+		{"rss sent as html", `<?xml version="1.0"?><rss version="2.0"></rss>`, "text/html", true},
+		{"comment before root", `<!-- generated by hugo --><rss version="2.0"><channel></channel></rss>`, "text/xml", true},
+		{"comment before atom, sent as html", "<!--\n  generated\n-->\n<feed xmlns=\"http://www.w3.org/2005/Atom\"></feed>", "text/html", true},
+		{"json feed", `{"version": "https://jsonfeed.org/version/1.1"}`, "application/feed+json", true},
+		{"html page", `<!DOCTYPE html><html><head><title>Hi</title></head></html>`, "text/html", false},
+		{"html with comment", `<!-- page --><html><body>Hi</body></html>`, "application/xml", false},
+		{"html sent as xml", `<html><body>Hi</body></html>`, "application/rss+xml", false},
+		{"image", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "application/octet-stream", false},
+	}
+	for _, tt := range tests {
+		if got := isFeedContent([]byte(tt.body), tt.contentType); got != tt.want {
+			t.Errorf("%s: isFeedContent = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFetchFeedConditional(t *testing.T) {
+	const etag = `"v1"`
+	const lastModified = "Mon, 02 Jan 2006 15:04:05 GMT"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			// validators are optional on a 304
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testFeed))
+	}))
+	defer server.Close()
+	client := NewClient(5*time.Second, DefaultMaxBodySize)
+
+	first, err := client.FetchFeed(context.Background(), server.URL, FetchOptions{})
+	if err != nil {
+		t.Fatalf("FetchFeed returned error: %v", err)
+	}
+	if first.NotModified || first.Feed == nil || first.ETag != etag || first.LastModified != lastModified {
+		t.Fatalf("first fetch = %+v, want the feed and its validators", first)
+	}
+
+	second, err := client.FetchFeed(context.Background(), server.URL, FetchOptions{ETag: first.ETag, LastModified: first.LastModified})
+	if err != nil {
+		t.Fatalf("FetchFeed returned error: %v", err)
+	}
+	if !second.NotModified || second.Feed != nil || second.StatusCode != http.StatusNotModified {
+		t.Errorf("second fetch = %+v, want not modified", second)
+	}
+	if second.ETag != etag || second.LastModified != lastModified {
+		t.Errorf("second fetch validators = %q %q, want the ones sent", second.ETag, second.LastModified)
+	}
+}
+
+func TestFetchFeedRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/moved", http.RedirectHandler("/feed", http.StatusMovedPermanently))
+	mux.Handle("/moved-twice", http.RedirectHandler("/moved-again", http.StatusPermanentRedirect))
+	mux.Handle("/moved-again", http.RedirectHandler("/feed", http.StatusMovedPermanently))
+	mux.Handle("/moved-then-temporary", http.RedirectHandler("/temporary", http.StatusMovedPermanently))
+	mux.Handle("/temporary", http.RedirectHandler("/feed", http.StatusFound))
+	mux.HandleFunc("/feed", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testFeed))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	client := NewClient(5*time.Second, DefaultMaxBodySize)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/feed", ""},
+		{"/moved", server.URL + "/feed"},
+		{"/moved-twice", server.URL + "/feed"},
+		{"/temporary", ""},
+		{"/moved-then-temporary", ""},
+	}
+	for _, tt := range tests {
+		result, err := client.FetchFeed(context.Background(), server.URL+tt.path, FetchOptions{})
+		if err != nil {
+			t.Errorf("%s: FetchFeed returned error: %v", tt.path, err)
+			continue
+		}
+		if result.PermanentURL != tt.want {
+			t.Errorf("%s: PermanentURL = %q, want %q", tt.path, result.PermanentURL, tt.want)
+		}
+	}
+}
+
+func TestFetchFeedContentEncoding(t *testing.T) {
+	var gzipped bytes.Buffer
+	writer := gzip.NewWriter(&gzipped)
+	writer.Write([]byte(testFeed))
+	writer.Close()
+	corrupt := bytes.Clone(gzipped.Bytes())
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		wantKind ErrorKind
+	}{
+		{"gzip", "gzip", gzipped.Bytes(), ""},
+		{"corrupt gzip", "gzip", corrupt, ErrorEncoding},
+		{"not gzip at all", "gzip", []byte(testFeed), ErrorEncoding},
+		{"unsupported encoding", "compress", []byte(testFeed), ErrorEncoding},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/rss+xml")
+				w.Header().Set("Content-Encoding", tt.encoding)
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			result, err := NewClient(5*time.Second, DefaultMaxBodySize).FetchFeed(context.Background(), server.URL, FetchOptions{Retries: 2})
+			if tt.wantKind == "" {
+				if err != nil || result.Feed == nil {
+					t.Errorf("FetchFeed = %+v, %v, want the feed", result, err)
+				}
+				return
+			}
+			var fetchErr *FetchError
+			if !errors.As(err, &fetchErr) || fetchErr.Kind != tt.wantKind || fetchErr.Temporary() || !errors.Is(err, ErrBodyEncoding) {
+				t.Errorf("FetchFeed error = %v, want a permanent %s error", err, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestFetchFeedTruncatedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("Content-Length", "1000")
+		w.Write([]byte{0x1f, 0x8b, 0x08, 0x00})
+	}))
+	defer server.Close()
+
+	_, err := NewClient(5*time.Second, DefaultMaxBodySize).FetchFeed(context.Background(), server.URL, FetchOptions{})
+	var fetchErr *FetchError
+	if !errors.As(err, &fetchErr) || fetchErr.Kind != ErrorNetwork {
+		t.Errorf("FetchFeed error = %v, want a network error", err)
+	}
+}
@@ -0,0 +1,145 @@
+package rss
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrNoDate = errors.New("no date")
+
+// layouts seen in the wild, tried in order. weekday names are stripped and
+// zone names are turned into offsets before parsing (see normalizeDate), so
+// none of the layouts below carry them.
+var dateLayouts = []string{
+	// RFC 822 / RFC 1123 and their sloppy variants
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04 -0700",
+	"2 Jan 06 15:04:05 -0700",
+	"2 Jan 06 15:04 -0700",
+	"2 January 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05",
+	"2-Jan-06 15:04:05 -0700",
+	"Jan 2 15:04:05 -0700 2006",
+	"Jan 2, 2006 15:04:05 -0700",
+	// same with a colon in the offset, as ISO 8601 writes it
+	"2 Jan 2006 15:04:05 -07:00",
+	"2 Jan 2006 15:04 -07:00",
+	"2 Jan 06 15:04:05 -07:00",
+	"2 Jan 06 15:04 -07:00",
+
+	// W3C-DTF, the ISO 8601 profile used by Atom (as RFC 3339), JSON Feed
+	// and Dublin Core's dc:date. it allows dropping seconds or the whole time.
+	time.RFC3339Nano,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+
+	// ISO 8601 variants that are not valid RFC 3339
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+}
+
+// offsets of the zone names RFC 822 allows plus the ones feeds use anyway.
+// time.Parse would accept any of these with a made-up zero offset.
+var zoneOffsets = map[string]string{
+	"UT":   "+0000",
+	"UTC":  "+0000",
+	"GMT":  "+0000",
+	"Z":    "+0000",
+	"EST":  "-0500",
+	"EDT":  "-0400",
+	"CST":  "-0600",
+	"CDT":  "-0500",
+	"MST":  "-0700",
+	"MDT":  "-0600",
+	"PST":  "-0800",
+	"PDT":  "-0700",
+	"AKST": "-0900",
+	"AKDT": "-0800",
+	"HST":  "-1000",
+	"BST":  "+0100",
+	"WET":  "+0000",
+	"WEST": "+0100",
+	"CET":  "+0100",
+	"CEST": "+0200",
+	"MET":  "+0100",
+	"MEST": "+0200",
+	"EET":  "+0200",
+	"EEST": "+0300",
+	"MSK":  "+0300",
+	"IST":  "+0530",
+	"SGT":  "+0800",
+	"HKT":  "+0800",
+	"JST":  "+0900",
+	"KST":  "+0900",
+	"AEST": "+1000",
+	"AEDT": "+1100",
+	"NZST": "+1200",
+	"NZDT": "+1300",
+}
+
+// ParseDate parses a feed date in any of the layouts publishers actually use.
+// it returns ErrNoDate when value is blank, so callers can tell a missing
+// date apart from one they couldn't read.
+func ParseDate(value string) (time.Time, error) {
+	normalized := normalizeDate(value)
+	if normalized == "" {
+		return time.Time{}, ErrNoDate
+	}
+
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, normalized); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unrecognized date format %q", value)
+}
+
+// normalizeDate trims the noise around a date so fewer layouts are needed:
+// comments like "(UTC)", a leading weekday, runs of whitespace and named
+// zones (replaced by their numeric offset).
+func normalizeDate(value string) string {
+	if i := strings.Index(value, "("); i >= 0 {
+		value = value[:i]
+	}
+
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return ""
+	}
+
+	// "Mon," / "Monday," / "Mon" followed by a day number
+	if first := strings.TrimSuffix(fields[0], ","); isWeekday(first) {
+		fields = fields[1:]
+	}
+
+	if len(fields) > 0 {
+		last := len(fields) - 1
+		if offset, ok := zoneOffsets[strings.ToUpper(fields[last])]; ok {
+			fields[last] = offset
+		}
+	}
+
+	return strings.Join(fields, " ")
+}
+
+func isWeekday(value string) bool {
+	if len(value) < 3 {
+		return false
+	}
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		name := day.String()
+		if strings.EqualFold(value, name) || strings.EqualFold(value, name[:3]) {
+			return true
+		}
+	}
+	return false
+}
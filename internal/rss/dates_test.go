@@ -0,0 +1,54 @@
+package rss
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"Mon, 02 Jan 2006 15:04:05 +0000", want},
+		{"Mon, 02 Jan 2006 15:04:05 GMT", want},
+		{"Mon, 02 Jan 2006 15:04:05 +00:00", want},
+		{"Monday, 02 Jan 2006 10:04:05 EST", want},
+		{"02 Jan 2006 17:04:05 +02:00", want},
+		{"Mon, 2 Jan 06 15:04:05 Z", want},
+		{"Mon, 02 Jan 2006 15:04:05 GMT (Coordinated Universal Time)", want},
+		{"Mon,  02   Jan 2006 15:04:05 ut", want},
+		{"Mon, 02 Jan 2006 15:04 +0000", want.Truncate(time.Minute)},
+		{"2006-01-02T15:04:05Z", want},
+		{"2006-01-02T17:04:05+02:00", want},
+		{"2006-01-02T15:04:05.000Z", want},
+		{"2006-01-02T15:04:05+0000", want},
+		{"2006-01-02 15:04:05", want},
+		{"2006-01-02", time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseDate(tt.value)
+		if err != nil {
+			t.Errorf("ParseDate(%q) returned error: %v", tt.value, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseDate(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseDateErrors(t *testing.T) {
+	for _, value := range []string{"", "   ", "(no date)"} {
+		if _, err := ParseDate(value); !errors.Is(err, ErrNoDate) {
+			t.Errorf("ParseDate(%q) error = %v, want ErrNoDate", value, err)
+		}
+	}
+	for _, value := range []string{"yesterday", "02/01/2006 15:04"} {
+		if _, err := ParseDate(value); err == nil || errors.Is(err, ErrNoDate) {
+			t.Errorf("ParseDate(%q) error = %v, want a format error", value, err)
+		}
+	}
+}
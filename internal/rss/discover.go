@@ -0,0 +1,127 @@
+package rss
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"mime"
+	"slices"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// FeedLink is a feed offered by a website, see DiscoverFeeds.
+type FeedLink struct {
+	URL   string
+	Title string
+	// Type is the media type the page advertised, empty for probed feeds
+	Type string
+}
+
+// media types pages use in <link rel="alternate"> to point at their feeds
+var feedLinkTypes = []string{
+	"application/rss+xml",
+	"application/atom+xml",
+	"application/feed+json",
+	"application/rdf+xml",
+}
+
+// where blog engines usually put their feed, tried when a page doesn't
+// advertise any
+var commonFeedPaths = []string{"/feed", "/rss", "/feed.xml", "/rss.xml", "/atom.xml", "/index.xml", "/feed.json"}
+
+// DiscoverFeeds finds the feeds of a website with DefaultClient.
+func DiscoverFeeds(ctx context.Context, page []byte, contentType, pageURL string) []FeedLink {
+	return DefaultClient.DiscoverFeeds(ctx, page, contentType, pageURL)
+}
+
+// DiscoverFeeds returns the feeds the HTML page fetched from pageURL links
+// to. when it doesn't link to any, the common feed locations of the site
+// are tried and the ones serving a feed are returned.
+func (c *Client) DiscoverFeeds(ctx context.Context, page []byte, contentType, pageURL string) []FeedLink {
+	if links := FindFeedLinks(page, contentType, pageURL); len(links) > 0 {
+		return links
+	}
+	return c.probeFeedPaths(ctx, pageURL)
+}
+
+// FindFeedLinks returns the feeds advertised in the <head> of an HTML page
+// with <link rel="alternate" type="application/rss+xml" href="...">, their
+// urls resolved against the page's <base> or pageURL.
+func FindFeedLinks(page []byte, contentType, pageURL string) []FeedLink {
+	reader, err := charset.NewReader(bytes.NewReader(page), contentType)
+	if err != nil {
+		return nil
+	}
+	doc, err := html.Parse(reader)
+	if err != nil {
+		return nil
+	}
+
+	base := pageURL
+	var links []FeedLink
+	for node := range doc.Descendants() {
+		if node.Type != html.ElementNode {
+			continue
+		}
+		switch node.DataAtom {
+		case atom.Base:
+			if href := attr(node, "href"); href != "" && base == pageURL {
+				base = ResolveURL(pageURL, href)
+			}
+		case atom.Link:
+			if !slices.Contains(strings.Fields(strings.ToLower(attr(node, "rel"))), "alternate") {
+				continue
+			}
+			mediaType, _, err := mime.ParseMediaType(attr(node, "type"))
+			if err != nil || !slices.Contains(feedLinkTypes, mediaType) {
+				continue
+			}
+			href := attr(node, "href")
+			if href == "" {
+				continue
+			}
+			links = append(links, FeedLink{URL: href, Title: attr(node, "title"), Type: mediaType})
+		}
+	}
+
+	// the hrefs are resolved last, <base> may come after a <link>
+	var found []FeedLink
+	for _, link := range links {
+		link.URL = ResolveURL(base, link.URL)
+		if !slices.ContainsFunc(found, func(other FeedLink) bool { return other.URL == link.URL }) {
+			found = append(found, link)
+		}
+	}
+	return found
+}
+
+func attr(node *html.Node, key string) string {
+	for _, a := range node.Attr {
+		if a.Namespace == "" && strings.EqualFold(a.Key, key) {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func (c *Client) probeFeedPaths(ctx context.Context, pageURL string) []FeedLink {
+	var found []FeedLink
+	for _, path := range commonFeedPaths {
+		candidate := ResolveURL(pageURL, path)
+		result, err := c.FetchFeed(ctx, candidate, FetchOptions{})
+		if err != nil {
+			continue
+		}
+		// /feed and /feed/ often end up being the same feed
+		feedURL := cmp.Or(result.PermanentURL, candidate)
+		if slices.ContainsFunc(found, func(other FeedLink) bool { return other.URL == feedURL }) {
+			continue
+		}
+		found = append(found, FeedLink{URL: feedURL, Title: result.Feed.Channel.Title})
+	}
+	return found
+}
@@ -0,0 +1,104 @@
+package rss
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFindFeedLinks(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want []FeedLink
+	}{
+		{
+			name: "rss and atom",
+			page: `<html><head>
+<link rel="alternate" type="application/rss+xml" title="RSS" href="/rss.xml">
+<link rel="alternate" type="application/atom+xml" title="Atom" href="https://example.com/atom.xml">
+<link rel="stylesheet" type="text/css" href="/style.css">
+</head></html>`,
+			want: []FeedLink{
+				{URL: "https://example.com/rss.xml", Title: "RSS", Type: "application/rss+xml"},
+				{URL: "https://example.com/atom.xml", Title: "Atom", Type: "application/atom+xml"},
+			},
+		},
+		{
+			name: "base after link",
+			page: `<html><head>
+<link rel="alternate" type="application/rss+xml" href="feed.xml">
+<base href="https://cdn.example.com/blog/">
+</head></html>`,
+			want: []FeedLink{{URL: "https://cdn.example.com/blog/feed.xml", Type: "application/rss+xml"}},
+		},
+		{
+			name: "relative base",
+			page: `<base href="/blog/"><link rel="alternate" type="application/feed+json" href="feed.json">`,
+			want: []FeedLink{{URL: "https://example.com/blog/feed.json", Type: "application/feed+json"}},
+		},
+		{
+			name: "rel tokens and type parameters",
+			page: `<LINK REL="Alternate Home" TYPE="application/RSS+xml; charset=utf-8" HREF="/rss">
+<link rel="alternatex" type="application/rss+xml" href="/not-this">
+<link rel="alternate" type="text/html" href="/fr/">
+<link rel="alternate" type="application/rss+xml">`,
+			want: []FeedLink{{URL: "https://example.com/rss", Type: "application/rss+xml"}},
+		},
+		{
+			name: "duplicates",
+			page: `<link rel="alternate" type="application/rss+xml" title="Posts" href="/feed">
+<link rel="alternate" type="application/rss+xml" title="Posts again" href="https://example.com/feed">
+<link rel="alternate" type="application/rss+xml" title="Comments" href="/comments/feed">`,
+			want: []FeedLink{
+				{URL: "https://example.com/feed", Title: "Posts", Type: "application/rss+xml"},
+				{URL: "https://example.com/comments/feed", Title: "Comments", Type: "application/rss+xml"},
+			},
+		},
+		{
+			name: "no feeds",
+			page: `<html><head><title>Hi</title></head></html>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindFeedLinks([]byte(tt.page), "text/html; charset=utf-8", "https://example.com/")
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FindFeedLinks = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscoverFeedsProbesCommonPaths(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>No feed links</title></head></html>`))
+	})
+	mux.Handle("/feed", http.RedirectHandler("/feed/", http.StatusMovedPermanently))
+	mux.HandleFunc("/feed/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/rss+xml")
+		w.Write([]byte(testFeed))
+	})
+	// html served where a feed is expected isn't a feed
+	mux.HandleFunc("/rss", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><body>Not found</body></html>`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	client := NewClient(5*time.Second, DefaultMaxBodySize)
+	links := client.DiscoverFeeds(context.Background(), []byte(`<html></html>`), "text/html", server.URL+"/")
+	if len(links) != 1 || links[0].URL != server.URL+"/feed/" {
+		t.Errorf("DiscoverFeeds = %+v, want only %s/feed/", links, server.URL)
+	}
+}
@@ -0,0 +1,127 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ErrorKind string
+
+const (
+	ErrorDNS     ErrorKind = "dns"
+	ErrorTimeout ErrorKind = "timeout"
+	ErrorNetwork ErrorKind = "network"
+	ErrorClient  ErrorKind = "client" // 4xx
+	ErrorServer  ErrorKind = "server" // 5xx
+	ErrorParse   ErrorKind = "parse"
+	// the body didn't decode per its Content-Encoding, see ErrBodyEncoding
+	ErrorEncoding ErrorKind = "encoding"
+	// the response was bigger than the client's MaxBodySize
+	ErrorTooLarge ErrorKind = "too_large"
+	// the response was something else than a feed, see NotFeedError
+	ErrorNotFeed ErrorKind = "not_feed"
+)
+
+const (
+	retryBaseDelay = time.Second
+	retryMaxDelay  = 30 * time.Second
+	// a server asking us to come back later than this is not retried within
+	// the same fetch, the caller can use RetryAfter to schedule the next one
+	maxRetryAfter = 2 * time.Minute
+)
+
+// FetchError is returned by FetchFeed for anything that went wrong after the
+// request was built, Kind tells what.
+type FetchError struct {
+	Kind       ErrorKind
+	StatusCode int
+	// RetryAfter is the delay the server asked for on 429/503, if any
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *FetchError) Error() string {
+	return fmt.Sprintf("%s error: %v", e.Kind, e.Err)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// Temporary reports whether the same request may succeed if retried later.
+func (e *FetchError) Temporary() bool {
+	switch e.Kind {
+	case ErrorTimeout, ErrorNetwork, ErrorServer:
+		return true
+	case ErrorDNS:
+		var dnsErr *net.DNSError
+		return errors.As(e.Err, &dnsErr) && (dnsErr.IsTemporary || dnsErr.IsTimeout)
+	case ErrorClient:
+		return e.StatusCode == http.StatusRequestTimeout || e.StatusCode == http.StatusTooManyRequests
+	default:
+		return false
+	}
+}
+
+// requestError classifies an error returned while sending the request or
+// reading the response.
+func requestError(err error) *FetchError {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &dnsErr):
+		return &FetchError{Kind: ErrorDNS, Err: err}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return &FetchError{Kind: ErrorTimeout, Err: err}
+	default:
+		return &FetchError{Kind: ErrorNetwork, Err: err}
+	}
+}
+
+func statusError(res *http.Response) *FetchError {
+	kind := ErrorClient
+	if res.StatusCode >= 500 {
+		kind = ErrorServer
+	}
+	return &FetchError{
+		Kind:       kind,
+		StatusCode: res.StatusCode,
+		RetryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+		Err:        fmt.Errorf("unexpected status: %s", res.Status),
+	}
+}
+
+// parseRetryAfter reads a Retry-After header, either a number of seconds or
+// an HTTP date. it returns 0 when the header is absent or invalid.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return max(time.Duration(seconds)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// retryDelay picks how long to wait before the given retry (0-based): the
+// server's Retry-After when it sent one, otherwise exponential backoff with
+// full jitter so many feeds failing together don't retry in lockstep.
+// ok is false when the server asked for more than we are willing to wait.
+func retryDelay(err *FetchError, retry int) (delay time.Duration, ok bool) {
+	if err.RetryAfter > 0 {
+		return err.RetryAfter, err.RetryAfter <= maxRetryAfter
+	}
+	backoff := min(retryBaseDelay<<retry, retryMaxDelay)
+	return rand.N(backoff) + 1, true
+}
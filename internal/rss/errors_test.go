@@ -0,0 +1,140 @@
+package rss
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, time.May, 5, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"120", 2 * time.Minute},
+		{" 5 ", 5 * time.Second},
+		{"-10", 0},
+		{"Sun, 05 May 2024 10:30:00 GMT", 30 * time.Minute},
+		{"Sunday, 05-May-24 10:00:10 GMT", 10 * time.Second},
+		{"Sun, 05 May 2024 09:00:00 GMT", 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.value, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		retry      int
+		min, max   time.Duration
+		ok         bool
+	}{
+		{"server delay", 30 * time.Second, 0, 30 * time.Second, 30 * time.Second, true},
+		{"server delay at the cutoff", maxRetryAfter, 0, maxRetryAfter, maxRetryAfter, true},
+		{"server delay past the cutoff", maxRetryAfter + time.Second, 0, maxRetryAfter + time.Second, maxRetryAfter + time.Second, false},
+		{"first backoff", 0, 0, 1, retryBaseDelay, true},
+		{"third backoff", 0, 2, 1, 4 * retryBaseDelay, true},
+		{"backoff is capped", 0, 20, 1, retryMaxDelay, true},
+	}
+	for _, tt := range tests {
+		for range 20 {
+			delay, ok := retryDelay(&FetchError{Kind: ErrorServer, RetryAfter: tt.retryAfter}, tt.retry)
+			if ok != tt.ok || delay < tt.min || delay > tt.max {
+				t.Errorf("%s: retryDelay = %v, %v, want [%v, %v], %v", tt.name, delay, ok, tt.min, tt.max, tt.ok)
+				break
+			}
+		}
+	}
+}
+
+func TestTemporary(t *testing.T) {
+	tests := []struct {
+		name string
+		err  *FetchError
+		want bool
+	}{
+		{"unknown host", requestError(&net.DNSError{Err: "no such host", Name: "nope.example", IsNotFound: true}), false},
+		{"dns timeout", requestError(&net.DNSError{Err: "i/o timeout", Name: "slow.example", IsTimeout: true}), true},
+		{"dns server failure", requestError(&net.DNSError{Err: "server misbehaving", Name: "flaky.example", IsTemporary: true}), true},
+		{"deadline", requestError(fmt.Errorf("get: %w", context.DeadlineExceeded)), true},
+		{"connection reset", requestError(errors.New("connection reset by peer")), true},
+		{"408", &FetchError{Kind: ErrorClient, StatusCode: http.StatusRequestTimeout}, true},
+		{"429", &FetchError{Kind: ErrorClient, StatusCode: http.StatusTooManyRequests}, true},
+		{"404", &FetchError{Kind: ErrorClient, StatusCode: http.StatusNotFound}, false},
+		{"503", &FetchError{Kind: ErrorServer, StatusCode: http.StatusServiceUnavailable}, true},
+		{"parse", &FetchError{Kind: ErrorParse}, false},
+		{"not a feed", &FetchError{Kind: ErrorNotFeed}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.err.Temporary(); got != tt.want {
+			t.Errorf("%s: Temporary = %v, want %v (kind %s)", tt.name, got, tt.want, tt.err.Kind)
+		}
+	}
+	if kind := requestError(&net.DNSError{Err: "no such host", IsNotFound: true}).Kind; kind != ErrorDNS {
+		t.Errorf("dns error kind = %s, want %s", kind, ErrorDNS)
+	}
+}
+
+func TestFetchFeedRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		failures     int32
+		status       int
+		retryAfter   string
+		retries      int
+		wantRequests int32
+		wantErr      bool
+	}{
+		{"recovers after temporary failures", 2, http.StatusServiceUnavailable, "", 2, 3, false},
+		{"gives up after the retries", 5, http.StatusBadGateway, "", 1, 2, true},
+		{"no retries", 5, http.StatusServiceUnavailable, "", 0, 1, true},
+		{"permanent failure", 5, http.StatusNotFound, "", 3, 1, true},
+		{"retry-after too long", 5, http.StatusTooManyRequests, "3600", 3, 1, true},
+		{"retry-after honored", 1, http.StatusTooManyRequests, "1", 1, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if requests.Add(1) <= tt.failures {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.status)
+					return
+				}
+				w.Header().Set("Content-Type", "application/rss+xml")
+				w.Write([]byte(testFeed))
+			}))
+			defer server.Close()
+
+			result, err := NewClient(5*time.Second, DefaultMaxBodySize).FetchFeed(context.Background(), server.URL, FetchOptions{Retries: tt.retries})
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("server got %d requests, want %d", got, tt.wantRequests)
+			}
+			if tt.wantErr {
+				var fetchErr *FetchError
+				if !errors.As(err, &fetchErr) || fetchErr.StatusCode != tt.status {
+					t.Errorf("FetchFeed error = %v, want a FetchError with status %d", err, tt.status)
+				}
+				return
+			}
+			if err != nil || result.Feed == nil {
+				t.Errorf("FetchFeed = %+v, %v, want the feed", result, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package rss
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type RSSFeed struct {
+	Channel struct {
+		Title string `xml:"title"`
+		// <atom:link rel="self"> is common in RSS feeds and a field without
+		// namespace matches it too, emptying Link when it comes after <link>
+		AtomLinks   []atomLink `xml:"http://www.w3.org/2005/Atom link"`
+		Link        string     `xml:"link"`
+		Description string     `xml:"description"`
+		Item        []RSSItem  `xml:"item"`
+		Language    string     `xml:"language"`
+		// podcasts often only have the itunes one, it has to come first
+		// since a field without namespace matches any <image>
+		ITunesImage struct {
+			Href string `xml:"href,attr"`
+		} `xml:"http://www.itunes.com/dtds/podcast-1.0.dtd image"`
+		Image struct {
+			URL string `xml:"url"`
+		} `xml:"image"`
+
+		// polling hints, see UpdateHint and Skips
+		TTL             string   `xml:"ttl"`
+		SkipHours       []string `xml:"skipHours>hour"`
+		SkipDays        []string `xml:"skipDays>day"`
+		UpdatePeriod    string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string   `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"channel"`
+}
+
+type RSSItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate"`
+	Date        string  `xml:"http://purl.org/dc/elements/1.1/ date"`
+	GUID        RSSGUID `xml:"guid"`
+	Updated     string  `xml:"http://www.w3.org/2005/Atom updated"`
+}
+
+type RSSGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink string `xml:"isPermaLink,attr"`
+}
+
+// Published parses the item's pubDate, falling back to dc:date which some
+// RSS 2.0 feeds use instead.
+func (item RSSItem) Published() (time.Time, error) {
+	value := item.PubDate
+	if strings.TrimSpace(value) == "" {
+		value = item.Date
+	}
+	return ParseDate(value)
+}
+
+// LastUpdated parses the date the item was last modified, when the feed
+// tells (atom:updated, JSON Feed's date_modified).
+func (item RSSItem) LastUpdated() (time.Time, error) {
+	return ParseDate(item.Updated)
+}
+
+// URL returns the absolute address of the item, resolved against base (the
+// site the feed belongs to). items without a link fall back to their guid
+// when it is a permalink, which is the default in RSS 2.0.
+func (item RSSItem) URL(base string) string {
+	link := strings.TrimSpace(item.Link)
+	if link == "" && item.GUID.IsPermaLink != "false" {
+		link = strings.TrimSpace(item.GUID.Value)
+	}
+	if link == "" {
+		return ""
+	}
+	return ResolveURL(base, link)
+}
+
+// ID identifies the item within its feed: the guid when the feed provides
+// one, otherwise a hash of link and title.
+func (item RSSItem) ID() string {
+	if guid := strings.TrimSpace(item.GUID.Value); guid != "" {
+		return guid
+	}
+	sum := sha256.Sum256([]byte(strings.TrimSpace(item.Link) + "\n" + strings.TrimSpace(item.Title)))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// ContentHash fingerprints what we store of the item, so edits made by the
+// author can be told apart from the same item being fetched again.
+func (item RSSItem) ContentHash() string {
+	sum := sha256.Sum256([]byte(item.Title + "\n" + item.Link + "\n" + item.Description))
+	return hex.EncodeToString(sum[:])
+}
+
+// IconURL returns the image the feed uses to represent itself, if any.
+func (f *RSSFeed) IconURL() string {
+	if icon := strings.TrimSpace(f.Channel.Image.URL); icon != "" {
+		return icon
+	}
+	return strings.TrimSpace(f.Channel.ITunesImage.Href)
+}
+
+// ResolveURL resolves ref against base, ref is returned as is when either
+// of them can't be parsed.
+func ResolveURL(base, ref string) string {
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ref
+	}
+	baseURL, err := url.Parse(strings.TrimSpace(base))
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
@@ -0,0 +1,65 @@
+package rss
+
+import "testing"
+
+func TestParseRSSChannelMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		doc      string
+		link     string
+		icon     string
+		language string
+	}{
+		{
+			name: "atom self link after link",
+			doc: `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom"><channel>
+  <title>Blog</title>
+  <link>https://example.com/</link>
+  <description>Recent content</description>
+  <language>en-us</language>
+  <atom:link href="https://example.com/index.xml" rel="self" type="application/rss+xml"/>
+</channel></rss>`,
+			link:     "https://example.com/",
+			language: "en-us",
+		},
+		{
+			name: "atom self link before link, itunes image",
+			doc: `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel>
+  <atom:link href="https://example.com/podcast.xml" rel="self"/>
+  <title>Podcast</title>
+  <link>https://example.com/podcast</link>
+  <itunes:image href="https://example.com/cover.jpg"/>
+</channel></rss>`,
+			link: "https://example.com/podcast",
+			icon: "https://example.com/cover.jpg",
+		},
+		{
+			name: "image over itunes image",
+			doc: `<rss version="2.0" xmlns:itunes="http://www.itunes.com/dtds/podcast-1.0.dtd"><channel>
+  <title>Podcast</title>
+  <link>/</link>
+  <image><url>https://example.com/logo.png</url><title>Podcast</title><link>/</link></image>
+  <itunes:image href="https://example.com/cover.jpg"/>
+</channel></rss>`,
+			link: "/",
+			icon: "https://example.com/logo.png",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.doc), "application/rss+xml")
+			if err != nil {
+				t.Fatalf("parseFeed returned error: %v", err)
+			}
+			if feed.Channel.Link != tt.link {
+				t.Errorf("Link = %q, want %q", feed.Channel.Link, tt.link)
+			}
+			if got := feed.IconURL(); got != tt.icon {
+				t.Errorf("IconURL() = %q, want %q", got, tt.icon)
+			}
+			if feed.Channel.Language != tt.language {
+				t.Errorf("Language = %q, want %q", feed.Channel.Language, tt.language)
+			}
+		})
+	}
+}
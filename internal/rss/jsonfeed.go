@@ -0,0 +1,82 @@
+package rss
+
+import (
+	"bytes"
+	"strings"
+)
+
+// jsonFeed follows the JSON Feed 1.1 spec (https://jsonfeed.org/version/1.1).
+// 1.0 documents decode the same way, we only read fields both versions share.
+type jsonFeed struct {
+	Version     string         `json:"version"`
+	Title       string         `json:"title"`
+	HomePageURL string         `json:"home_page_url"`
+	FeedURL     string         `json:"feed_url"`
+	Description string         `json:"description"`
+	Icon        string         `json:"icon"`
+	Favicon     string         `json:"favicon"`
+	Language    string         `json:"language"`
+	Items       []jsonFeedItem `json:"items"`
+}
+
+type jsonFeedItem struct {
+	ID            string `json:"id"`
+	URL           string `json:"url"`
+	ExternalURL   string `json:"external_url"`
+	Title         string `json:"title"`
+	ContentHTML   string `json:"content_html"`
+	ContentText   string `json:"content_text"`
+	Summary       string `json:"summary"`
+	DatePublished string `json:"date_published"`
+	DateModified  string `json:"date_modified"`
+}
+
+func isJSONFeed(data []byte, contentType string) bool {
+	if strings.Contains(strings.ToLower(contentType), "json") {
+		return true
+	}
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("{"))
+}
+
+func (f *jsonFeed) toRSS() *RSSFeed {
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = f.Title
+	rssFeed.Channel.Link = f.HomePageURL
+	rssFeed.Channel.Description = f.Description
+	rssFeed.Channel.Language = f.Language
+	rssFeed.Channel.Image.URL = f.Icon
+	if rssFeed.Channel.Image.URL == "" {
+		rssFeed.Channel.Image.URL = f.Favicon
+	}
+
+	for _, item := range f.Items {
+		link := item.URL
+		if link == "" {
+			link = item.ExternalURL
+		}
+
+		description := item.ContentHTML
+		if description == "" {
+			description = item.ContentText
+		}
+		if description == "" {
+			description = item.Summary
+		}
+
+		pubDate := item.DatePublished
+		if pubDate == "" {
+			pubDate = item.DateModified
+		}
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			Title:       item.Title,
+			Link:        link,
+			Description: description,
+			PubDate:     pubDate,
+			GUID:        RSSGUID{Value: item.ID, IsPermaLink: "false"},
+			Updated:     item.DateModified,
+		})
+	}
+
+	return &rssFeed
+}
@@ -0,0 +1,72 @@
+package rss
+
+import "testing"
+
+func TestParseJSONFeed(t *testing.T) {
+	tests := []struct {
+		name        string
+		doc         string
+		contentType string
+		want        RSSItem
+		wantErr     bool
+	}{
+		{
+			name:        "content_html and date_published",
+			contentType: "application/feed+json",
+			doc: `{"version": "https://jsonfeed.org/version/1.1", "title": "Example", "home_page_url": "https://example.com/",
+				"items": [{"id": "1", "url": "https://example.com/1", "title": "First", "content_html": "<p>Hi</p>",
+				"content_text": "Hi", "date_published": "2006-01-02T15:04:05Z", "date_modified": "2006-01-03T15:04:05Z"}]}`,
+			want: RSSItem{
+				Title:       "First",
+				Link:        "https://example.com/1",
+				Description: "<p>Hi</p>",
+				PubDate:     "2006-01-02T15:04:05Z",
+				GUID:        RSSGUID{Value: "1", IsPermaLink: "false"},
+				Updated:     "2006-01-03T15:04:05Z",
+			},
+		},
+		{
+			name:        "sniffed, fallbacks",
+			contentType: "text/plain",
+			doc: ` {"version": "https://jsonfeed.org/version/1", "title": "Example",
+				"items": [{"id": "2", "external_url": "https://other.example/2", "summary": "Sum",
+				"date_modified": "2006-01-02T15:04:05Z"}]}`,
+			want: RSSItem{
+				Link:        "https://other.example/2",
+				Description: "Sum",
+				PubDate:     "2006-01-02T15:04:05Z",
+				GUID:        RSSGUID{Value: "2", IsPermaLink: "false"},
+				Updated:     "2006-01-02T15:04:05Z",
+			},
+		},
+		{
+			name:        "not a JSON Feed",
+			contentType: "application/json",
+			doc:         `{"title": "Example", "items": []}`,
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			feed, err := parseFeed([]byte(tt.doc), tt.contentType)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("parseFeed returned no error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFeed returned error: %v", err)
+			}
+			if feed.Channel.Title != "Example" {
+				t.Errorf("title = %q, want %q", feed.Channel.Title, "Example")
+			}
+			if len(feed.Channel.Item) != 1 {
+				t.Fatalf("got %d items, want 1", len(feed.Channel.Item))
+			}
+			if got := feed.Channel.Item[0]; got != tt.want {
+				t.Errorf("item = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
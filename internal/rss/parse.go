@@ -0,0 +1,76 @@
+package rss
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// parseFeed tells JSON Feed apart by Content-Type or by sniffing the body,
+// otherwise looks at the root element of the XML document to tell which format
+// it is, then normalizes it into an RSSFeed so callers only deal with one model.
+func parseFeed(data []byte, contentType string) (*RSSFeed, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	data, settled, err := decodeCharset(data, contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	if isJSONFeed(data, contentType) {
+		var feed jsonFeed
+		if err := json.Unmarshal(data, &feed); err != nil {
+			return nil, err
+		}
+		if !strings.HasPrefix(feed.Version, "https://jsonfeed.org/version/") {
+			return nil, errors.New("unsupported JSON document: missing JSON Feed version")
+		}
+		return feed.toRSS(), nil
+	}
+
+	root, err := rootElement(newXMLDecoder(data, settled))
+	if err != nil {
+		return nil, err
+	}
+
+	switch root {
+	case "rss":
+		var rssFeed RSSFeed
+		if err := newXMLDecoder(data, settled).Decode(&rssFeed); err != nil {
+			return nil, err
+		}
+		return &rssFeed, nil
+	case "feed":
+		var atom atomFeed
+		if err := newXMLDecoder(data, settled).Decode(&atom); err != nil {
+			return nil, err
+		}
+		return atom.toRSS(), nil
+	case "rdf":
+		var rdf rdfFeed
+		if err := newXMLDecoder(data, settled).Decode(&rdf); err != nil {
+			return nil, err
+		}
+		return rdf.toRSS(), nil
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root)
+	}
+}
+
+func rootElement(decoder *xml.Decoder) (string, error) {
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return "", errors.New("empty feed document")
+		}
+		if err != nil {
+			return "", err
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return strings.ToLower(start.Name.Local), nil
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package rss
+
+import "encoding/xml"
+
+// rdfFeed is RSS 1.0: unlike 2.0 the items are siblings of the channel
+// instead of its children, and dates come from Dublin Core.
+type rdfFeed struct {
+	XMLName xml.Name `xml:"RDF"`
+	Channel struct {
+		Title       string `xml:"title"`
+		Link        string `xml:"link"`
+		Description string `xml:"description"`
+		Language    string `xml:"http://purl.org/dc/elements/1.1/ language"`
+
+		UpdatePeriod    string `xml:"http://purl.org/rss/1.0/modules/syndication/ updatePeriod"`
+		UpdateFrequency string `xml:"http://purl.org/rss/1.0/modules/syndication/ updateFrequency"`
+	} `xml:"channel"`
+	// like items, the image is a sibling the channel only points to
+	Image struct {
+		URL string `xml:"url"`
+	} `xml:"image"`
+	Items []rdfItem `xml:"item"`
+}
+
+type rdfItem struct {
+	About       string `xml:"about,attr"`
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	Description string `xml:"description"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+}
+
+func (f *rdfFeed) toRSS() *RSSFeed {
+	var rssFeed RSSFeed
+	rssFeed.Channel.Title = f.Channel.Title
+	rssFeed.Channel.Link = f.Channel.Link
+	rssFeed.Channel.Description = f.Channel.Description
+	rssFeed.Channel.Language = f.Channel.Language
+	rssFeed.Channel.Image.URL = f.Image.URL
+	rssFeed.Channel.UpdatePeriod = f.Channel.UpdatePeriod
+	rssFeed.Channel.UpdateFrequency = f.Channel.UpdateFrequency
+
+	for _, item := range f.Items {
+		// rdf:about is required and is the item's URI, usually its link
+		link := item.Link
+		if link == "" {
+			link = item.About
+		}
+
+		rssFeed.Channel.Item = append(rssFeed.Channel.Item, RSSItem{
+			Title:       item.Title,
+			Link:        link,
+			Description: item.Description,
+			Date:        item.Date,
+			GUID:        RSSGUID{Value: item.About, IsPermaLink: "false"},
+		})
+	}
+
+	return &rssFeed
+}
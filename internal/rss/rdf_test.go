@@ -0,0 +1,52 @@
+package rss
+
+import "testing"
+
+func TestParseRDFFeed(t *testing.T) {
+	doc := `<?xml version="1.0"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/"
+  xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:sy="http://purl.org/rss/1.0/modules/syndication/">
+  <channel rdf:about="https://example.com/">
+    <title>Example</title>
+    <link>https://example.com/</link>
+    <dc:language>en</dc:language>
+    <sy:updatePeriod>daily</sy:updatePeriod>
+  </channel>
+  <image rdf:about="https://example.com/logo.png"><url>https://example.com/logo.png</url></image>
+  <item rdf:about="https://example.com/1">
+    <title>First</title>
+    <link>https://example.com/1?utm=rss</link>
+    <dc:date>2006-01-02T15:04:05Z</dc:date>
+  </item>
+  <item rdf:about="https://example.com/2">
+    <title>Second</title>
+  </item>
+</rdf:RDF>`
+
+	feed, err := parseFeed([]byte(doc), "application/rdf+xml")
+	if err != nil {
+		t.Fatalf("parseFeed returned error: %v", err)
+	}
+	if feed.Channel.Title != "Example" || feed.Channel.Link != "https://example.com/" || feed.Channel.Language != "en" || feed.Channel.UpdatePeriod != "daily" {
+		t.Errorf("unexpected channel %+v", feed.Channel)
+	}
+	if got := feed.IconURL(); got != "https://example.com/logo.png" {
+		t.Errorf("IconURL() = %q", got)
+	}
+
+	want := []RSSItem{
+		{Title: "First", Link: "https://example.com/1?utm=rss", Date: "2006-01-02T15:04:05Z", GUID: RSSGUID{Value: "https://example.com/1", IsPermaLink: "false"}},
+		{Title: "Second", Link: "https://example.com/2", GUID: RSSGUID{Value: "https://example.com/2", IsPermaLink: "false"}},
+	}
+	if len(feed.Channel.Item) != len(want) {
+		t.Fatalf("got %d items, want %d", len(feed.Channel.Item), len(want))
+	}
+	for i, item := range feed.Channel.Item {
+		if item != want[i] {
+			t.Errorf("item %d = %+v, want %+v", i, item, want[i])
+		}
+	}
+	if _, err := feed.Channel.Item[0].Published(); err != nil {
+		t.Errorf("Published() returned error: %v", err)
+	}
+}
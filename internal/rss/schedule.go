@@ -0,0 +1,82 @@
+package rss
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// how many of the latest items PostingInterval looks at, older ones say
+// little about how often the feed is updated today.
+const postingSampleSize = 10
+
+var updatePeriods = map[string]time.Duration{
+	"hourly":  time.Hour,
+	"daily":   24 * time.Hour,
+	"weekly":  7 * 24 * time.Hour,
+	"monthly": 30 * 24 * time.Hour,
+	"yearly":  365 * 24 * time.Hour,
+}
+
+// UpdateHint is how often the publisher says the feed is worth polling, the
+// longest of <ttl> (minutes) and sy:updatePeriod divided by sy:updateFrequency.
+// it is zero when the feed gives no hint.
+func (f *RSSFeed) UpdateHint() time.Duration {
+	var hint time.Duration
+
+	if ttl, err := strconv.Atoi(strings.TrimSpace(f.Channel.TTL)); err == nil && ttl > 0 {
+		hint = time.Duration(ttl) * time.Minute
+	}
+
+	if period, ok := updatePeriods[strings.ToLower(strings.TrimSpace(f.Channel.UpdatePeriod))]; ok {
+		frequency, err := strconv.Atoi(strings.TrimSpace(f.Channel.UpdateFrequency))
+		if err != nil || frequency < 1 {
+			frequency = 1
+		}
+		hint = max(hint, period/time.Duration(frequency))
+	}
+
+	return hint
+}
+
+// Skips reports whether t falls in the <skipHours> (hours in GMT) or
+// <skipDays> the publisher asked aggregators not to poll in.
+func (f *RSSFeed) Skips(t time.Time) bool {
+	t = t.UTC()
+	for _, hour := range f.Channel.SkipHours {
+		// both 0 and 24 are used for midnight
+		if h, err := strconv.Atoi(strings.TrimSpace(hour)); err == nil && h%24 == t.Hour() {
+			return true
+		}
+	}
+	for _, day := range f.Channel.SkipDays {
+		if strings.EqualFold(strings.TrimSpace(day), t.Weekday().String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// PostingInterval estimates the time between posts from the latest item
+// dates, counting the time since the newest one so feeds that went quiet
+// look as slow as they are. ok is false when no item has a usable date.
+func (f *RSSFeed) PostingInterval(now time.Time) (interval time.Duration, ok bool) {
+	var dates []time.Time
+	for _, item := range f.Channel.Item {
+		if published, err := item.Published(); err == nil && !published.After(now) {
+			dates = append(dates, published)
+		}
+	}
+	if len(dates) == 0 {
+		return 0, false
+	}
+
+	slices.SortFunc(dates, func(a, b time.Time) int { return b.Compare(a) })
+	if len(dates) > postingSampleSize {
+		dates = dates[:postingSampleSize]
+	}
+
+	// from the oldest sampled post to now, spread over the posts in between
+	return now.Sub(dates[len(dates)-1]) / time.Duration(len(dates)), true
+}
@@ -0,0 +1,96 @@
+package rss
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUpdateHint(t *testing.T) {
+	tests := []struct {
+		ttl, period, frequency string
+		want                   time.Duration
+	}{
+		{"", "", "", 0},
+		{"60", "", "", time.Hour},
+		{"abc", "", "", 0},
+		{"-5", "", "", 0},
+		{"", "daily", "", 24 * time.Hour},
+		{"", "Hourly", "0", time.Hour},
+		{"", "daily", "2", 12 * time.Hour},
+		{"", "fortnightly", "", 0},
+		// the longest hint wins
+		{"60", "daily", "2", 12 * time.Hour},
+		{"600", "hourly", "", 10 * time.Hour},
+	}
+	for _, tt := range tests {
+		var feed RSSFeed
+		feed.Channel.TTL = tt.ttl
+		feed.Channel.UpdatePeriod = tt.period
+		feed.Channel.UpdateFrequency = tt.frequency
+		if got := feed.UpdateHint(); got != tt.want {
+			t.Errorf("UpdateHint(ttl=%q, period=%q, frequency=%q) = %v, want %v", tt.ttl, tt.period, tt.frequency, got, tt.want)
+		}
+	}
+}
+
+func TestSkips(t *testing.T) {
+	// a Sunday
+	midnight := time.Date(2024, time.May, 5, 0, 30, 0, 0, time.UTC)
+	paris := time.FixedZone("CEST", 2*60*60)
+
+	tests := []struct {
+		name  string
+		hours []string
+		days  []string
+		at    time.Time
+		want  bool
+	}{
+		{"no skips", nil, nil, midnight, false},
+		{"hour 0 is midnight", []string{"0"}, nil, midnight, true},
+		{"hour 24 is midnight too", []string{"24"}, nil, midnight, true},
+		{"other hour", []string{"1", "23"}, nil, midnight, false},
+		{"hours are GMT", []string{"0"}, nil, midnight.In(paris), true},
+		{"local hour doesn't count", []string{"2"}, nil, midnight.In(paris), false},
+		{"day", nil, []string{" sunday "}, midnight, true},
+		{"other day", nil, []string{"Saturday"}, midnight, false},
+	}
+	for _, tt := range tests {
+		var feed RSSFeed
+		feed.Channel.SkipHours = tt.hours
+		feed.Channel.SkipDays = tt.days
+		if got := feed.Skips(tt.at); got != tt.want {
+			t.Errorf("%s: Skips = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPostingInterval(t *testing.T) {
+	now := time.Date(2024, time.May, 5, 12, 0, 0, 0, time.UTC)
+	dated := func(ago ...time.Duration) RSSFeed {
+		var feed RSSFeed
+		for _, d := range ago {
+			feed.Channel.Item = append(feed.Channel.Item, RSSItem{PubDate: now.Add(-d).Format(time.RFC1123Z)})
+		}
+		return feed
+	}
+
+	tests := []struct {
+		name   string
+		feed   RSSFeed
+		want   time.Duration
+		wantOK bool
+	}{
+		{"no items", dated(), 0, false},
+		{"undated items", RSSFeed{}, 0, false},
+		{"one post", dated(10 * time.Hour), 10 * time.Hour, true},
+		{"daily posts", dated(0, 24*time.Hour, 48*time.Hour), 16 * time.Hour, true},
+		{"future posts ignored", dated(-time.Hour, 6*time.Hour), 6 * time.Hour, true},
+		{"only the latest posts count", dated(1*time.Hour, 2*time.Hour, 3*time.Hour, 4*time.Hour, 5*time.Hour, 6*time.Hour, 7*time.Hour, 8*time.Hour, 9*time.Hour, 10*time.Hour, 1000*time.Hour), time.Hour, true},
+	}
+	for _, tt := range tests {
+		got, ok := tt.feed.PostingInterval(now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("%s: PostingInterval = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -59,6 +59,15 @@ func main() {
 	if err := commands.Register("feeds", cli.HandlerGetFeeds); err != nil {
 		log.Fatalf("error registering feeds command: %v", err)
 	}
+	if err := commands.Register("feedstatus", cli.HandlerFeedStatus); err != nil {
+		log.Fatalf("error registering feedstatus command: %v", err)
+	}
+	if err := commands.Register("resumefeed", cli.HandlerResumeFeed); err != nil {
+		log.Fatalf("error registering resumefeed command: %v", err)
+	}
+	if err := commands.Register("feedhistory", cli.HandlerFeedHistory); err != nil {
+		log.Fatalf("error registering feedhistory command: %v", err)
+	}
 	if err := commands.Register("follow", cli.MiddlewareLoggedIn(cli.HandlerFollow)); err != nil {
 		log.Fatalf("error registering follow command: %v", err)
 	}
@@ -71,6 +80,45 @@ func main() {
 	if err := commands.Register("browse", cli.MiddlewareLoggedIn(cli.HandlerBrowse)); err != nil {
 		log.Fatalf("error registering browse command: %v", err)
 	}
+	if err := commands.Register("revisions", cli.HandlerRevisions); err != nil {
+		log.Fatalf("error registering revisions command: %v", err)
+	}
+	if err := commands.Register("read", cli.MiddlewareLoggedIn(cli.HandlerRead)); err != nil {
+		log.Fatalf("error registering read command: %v", err)
+	}
+	if err := commands.Register("mark-read", cli.MiddlewareLoggedIn(cli.HandlerMarkRead)); err != nil {
+		log.Fatalf("error registering mark-read command: %v", err)
+	}
+	if err := commands.Register("star", cli.MiddlewareLoggedIn(cli.HandlerStar)); err != nil {
+		log.Fatalf("error registering star command: %v", err)
+	}
+	if err := commands.Register("unstar", cli.MiddlewareLoggedIn(cli.HandlerUnstar)); err != nil {
+		log.Fatalf("error registering unstar command: %v", err)
+	}
+	if err := commands.Register("starred", cli.MiddlewareLoggedIn(cli.HandlerStarred)); err != nil {
+		log.Fatalf("error registering starred command: %v", err)
+	}
+	if err := commands.Register("save", cli.MiddlewareLoggedIn(cli.HandlerSave)); err != nil {
+		log.Fatalf("error registering save command: %v", err)
+	}
+	if err := commands.Register("unsave", cli.MiddlewareLoggedIn(cli.HandlerUnsave)); err != nil {
+		log.Fatalf("error registering unsave command: %v", err)
+	}
+	if err := commands.Register("saved", cli.MiddlewareLoggedIn(cli.HandlerSaved)); err != nil {
+		log.Fatalf("error registering saved command: %v", err)
+	}
+	if err := commands.Register("prune", cli.HandlerPrune); err != nil {
+		log.Fatalf("error registering prune command: %v", err)
+	}
+	if err := commands.Register("import", cli.MiddlewareLoggedIn(cli.HandlerImport)); err != nil {
+		log.Fatalf("error registering import command: %v", err)
+	}
+	if err := commands.Register("export", cli.MiddlewareLoggedIn(cli.HandlerExport)); err != nil {
+		log.Fatalf("error registering export command: %v", err)
+	}
+	if err := commands.Register("search", cli.MiddlewareLoggedIn(cli.HandlerSearch)); err != nil {
+		log.Fatalf("error registering search command: %v", err)
+	}
 
 	if len(os.Args) < 2 {
 		fmt.Fprintln(os.Stderr, "error: command name is missing")
@@ -2,9 +2,9 @@
 WITH
   inserted_feed_follow AS (
     INSERT INTO
-      feed_follows (id, created_at, updated_at, user_id, feed_id)
+      feed_follows (id, created_at, updated_at, user_id, feed_id, category)
     VALUES
-      ($1, $2, $3, $4, $5) RETURNING *
+      ($1, $2, $3, $4, $5, $6) RETURNING *
   )
 SELECT
   inserted_feed_follow.*,
@@ -23,6 +23,21 @@ FROM
 WHERE
   user_id = $1;
 
+-- name: GetFollowedFeedsForUser :many
+SELECT
+  feeds.name,
+  feeds.url,
+  feeds.site_url,
+  feed_follows.category
+FROM
+  feed_follows
+  INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+WHERE
+  feed_follows.user_id = $1
+ORDER BY
+  feed_follows.category NULLS FIRST,
+  feeds.name;
+
 -- name: GetFeedFollowByFeedId :one
 SELECT
   *,
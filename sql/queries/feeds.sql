@@ -11,27 +11,211 @@ FROM
   feeds;
 
 -- name: GetFeedByUrl :one
+-- also finds feeds by the urls they had before moving
 SELECT
   *
 FROM
   feeds
 WHERE
-  url = $1;
+  feeds.url = $1
+  OR feeds.id IN (
+    SELECT
+      feed_id
+    FROM
+      feed_aliases
+    WHERE
+      feed_aliases.url = $1
+  )
+ORDER BY
+  feeds.url = $1 DESC
+LIMIT
+  1;
+
+-- name: ClaimFeedsToFetch :many
+-- SKIP LOCKED lets several aggregators claim from the same table without
+-- waiting on each other, the lease hands the feed back if its claimer dies.
+-- leases are timed with the database clock, the one they are checked against.
+UPDATE feeds
+SET
+  updated_at = Now(),
+  claimed_by = sqlc.arg(claimed_by)::text,
+  locked_until = Now() + make_interval(secs => sqlc.arg(lease_seconds)::float8)
+WHERE
+  feeds.id IN (
+    SELECT
+      id
+    FROM
+      feeds
+    WHERE
+      (
+        next_fetch_at IS NULL
+        OR next_fetch_at <= Now()
+      )
+      AND (
+        locked_until IS NULL
+        OR locked_until < Now()
+      )
+      AND paused_at IS NULL
+    ORDER BY
+      next_fetch_at NULLS FIRST
+    LIMIT
+      sqlc.arg(batch_size)
+    FOR UPDATE
+      SKIP LOCKED
+  ) RETURNING *;
+
+-- name: RenewFeedLease :execrows
+-- extends our lease on a claimed feed, nothing is updated once another
+-- instance took it over
+UPDATE feeds
+SET
+  locked_until = Now() + make_interval(secs => sqlc.arg(lease_seconds)::float8)
+WHERE
+  feeds.id = sqlc.arg(id)
+  AND claimed_by = sqlc.arg(claimed_by)::text;
 
 -- name: MarkFeedFetched :exec
+-- next_fetch_at is relative to the database clock, like last_fetched_at and
+-- the due check in ClaimFeedsToFetch
 UPDATE feeds
 SET
   updated_at = Now(),
-  last_fetched_at = Now()
+  last_fetched_at = Now(),
+  claimed_by = NULL,
+  locked_until = NULL,
+  next_fetch_at = Now() + make_interval(secs => sqlc.arg(next_fetch_in)::float8)
+WHERE
+  feeds.id = sqlc.arg(id)
+  AND claimed_by = sqlc.arg(claimed_by)::text;
+
+-- name: ScheduleFeed :exec
+-- for feeds fetched outside of agg, see MarkFeedFetched
+UPDATE feeds
+SET
+  last_fetched_at = Now(),
+  next_fetch_at = Now() + make_interval(secs => sqlc.arg(next_fetch_in)::float8)
+WHERE
+  feeds.id = sqlc.arg(id);
+
+-- name: SetFeedCacheValidators :exec
+UPDATE feeds
+SET
+  etag = $2,
+  last_modified = $3
+WHERE
+  feeds.id = $1;
+
+-- name: UpdateFeedMetadata :exec
+UPDATE feeds
+SET
+  site_url = $2,
+  description = $3,
+  icon_url = $4,
+  language = $5
+WHERE
+  feeds.id = $1;
+
+-- name: RecordFeedError :one
+-- the feed is paused once it failed max_failures times in a row (0 never pauses)
+UPDATE feeds
+SET
+  last_error = sqlc.arg(last_error),
+  last_error_at = Now(),
+  last_status_code = sqlc.arg(last_status_code),
+  consecutive_failures = consecutive_failures + 1,
+  paused_at = CASE
+    WHEN paused_at IS NULL
+    AND sqlc.arg(max_failures)::int > 0
+    AND consecutive_failures + 1 >= sqlc.arg(max_failures)::int THEN Now()
+    ELSE paused_at
+  END
+WHERE
+  feeds.id = sqlc.arg(id) RETURNING *;
+
+-- name: RecordFeedSuccess :exec
+UPDATE feeds
+SET
+  consecutive_failures = 0,
+  last_success_at = Now(),
+  last_status_code = $2
 WHERE
   feeds.id = $1;
 
--- name: GetNextFeedToFetch :one
+-- name: GetBrokenFeeds :many
 SELECT
   *
 FROM
   feeds
+WHERE
+  consecutive_failures > 0
+  OR paused_at IS NOT NULL
 ORDER BY
-  last_fetched_at NULLS FIRST
-LIMIT
-  1;
+  paused_at NULLS LAST,
+  consecutive_failures DESC;
+
+-- name: ResumeFeed :execrows
+UPDATE feeds
+SET
+  paused_at = NULL,
+  consecutive_failures = 0,
+  next_fetch_at = NULL
+WHERE
+  url = $1;
+
+-- name: MoveFeedUrl :one
+-- the old url is kept as an alias and the move is logged in feed_history
+WITH
+  old_feed AS (
+    SELECT
+      feeds.id,
+      feeds.url
+    FROM
+      feeds
+    WHERE
+      feeds.id = sqlc.arg(id)
+  ),
+  alias AS (
+    INSERT INTO
+      feed_aliases (url, feed_id, created_at)
+    SELECT
+      old_feed.url,
+      old_feed.id,
+      Now()
+    FROM
+      old_feed
+    ON CONFLICT (url) DO NOTHING
+  ),
+  moved_back AS (
+    DELETE FROM feed_aliases
+    WHERE
+      feed_aliases.url = sqlc.arg(new_url)::text
+      AND feed_aliases.feed_id = sqlc.arg(id)
+  ),
+  history AS (
+    INSERT INTO
+      feed_history (id, created_at, feed_id, event, detail)
+    SELECT
+      sqlc.arg(history_id)::uuid,
+      Now(),
+      old_feed.id,
+      'moved',
+      old_feed.url || ' -> ' || sqlc.arg(new_url)::text
+    FROM
+      old_feed
+  )
+UPDATE feeds
+SET
+  updated_at = Now(),
+  url = sqlc.arg(new_url)::text
+WHERE
+  feeds.id = sqlc.arg(id) RETURNING *;
+
+-- name: GetFeedHistory :many
+SELECT
+  *
+FROM
+  feed_history
+WHERE
+  feed_id = $1
+ORDER BY
+  created_at DESC;
@@ -0,0 +1,164 @@
+-- name: MarkPostRead :execrows
+INSERT INTO
+  post_states (user_id, post_id, read, read_at)
+SELECT
+  sqlc.arg(user_id)::uuid,
+  posts.id,
+  TRUE,
+  Now()
+FROM
+  posts
+WHERE
+  posts.id = sqlc.arg(post_id)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET
+  read = TRUE,
+  read_at = Now();
+
+-- name: MarkPostsRead :execrows
+-- marks the unread posts of the followed feeds, optionally only those of one
+-- feed and/or published before a date
+INSERT INTO
+  post_states (user_id, post_id, read, read_at)
+SELECT
+  feed_follows.user_id,
+  posts.id,
+  TRUE,
+  Now()
+FROM
+  posts
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+WHERE
+  feed_follows.user_id = sqlc.arg(user_id)
+  AND (
+    sqlc.narg(feed_id)::uuid IS NULL
+    OR posts.feed_id = sqlc.narg(feed_id)
+  )
+  AND (
+    sqlc.narg(before)::timestamp IS NULL
+    OR posts.published_at < sqlc.narg(before)
+  )
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET
+  read = TRUE,
+  read_at = Now()
+WHERE
+  NOT post_states.read;
+
+-- name: GetUnreadCountsForUser :many
+SELECT
+  feeds.name,
+  feeds.url,
+  feed_follows.category,
+  COUNT(posts.id) FILTER (
+    WHERE
+      post_states.read IS NOT TRUE
+  ) AS unread
+FROM
+  feed_follows
+  INNER JOIN feeds ON feeds.id = feed_follows.feed_id
+  LEFT JOIN posts ON posts.feed_id = feeds.id
+  LEFT JOIN post_states ON post_states.post_id = posts.id
+  AND post_states.user_id = feed_follows.user_id
+WHERE
+  feed_follows.user_id = $1
+GROUP BY
+  feeds.id,
+  feed_follows.category
+ORDER BY
+  feeds.name;
+
+-- name: SetPostStarred :execrows
+-- starring an already starred post keeps the date it was first starred
+INSERT INTO
+  post_states (user_id, post_id, starred_at)
+SELECT
+  sqlc.arg(user_id)::uuid,
+  posts.id,
+  CASE
+    WHEN sqlc.arg(starred)::boolean THEN Now()
+  END
+FROM
+  posts
+WHERE
+  posts.id = sqlc.arg(post_id)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET
+  starred_at = CASE
+    WHEN sqlc.arg(starred)::boolean THEN COALESCE(post_states.starred_at, Now())
+  END;
+
+-- name: SetPostSaved :execrows
+INSERT INTO
+  post_states (user_id, post_id, saved_at)
+SELECT
+  sqlc.arg(user_id)::uuid,
+  posts.id,
+  CASE
+    WHEN sqlc.arg(saved)::boolean THEN Now()
+  END
+FROM
+  posts
+WHERE
+  posts.id = sqlc.arg(post_id)
+ON CONFLICT (user_id, post_id) DO UPDATE
+SET
+  saved_at = CASE
+    WHEN sqlc.arg(saved)::boolean THEN COALESCE(post_states.saved_at, Now())
+  END;
+
+-- name: GetStarredPosts :many
+SELECT
+  posts.id,
+  posts.created_at,
+  posts.updated_at,
+  posts.title,
+  posts.url,
+  posts.description,
+  posts.published_at,
+  posts.feed_id,
+  posts.guid,
+  posts.content_hash,
+  posts.source_updated_at,
+  feeds.name AS feed_name,
+  post_states.starred_at
+FROM
+  post_states
+  INNER JOIN posts ON posts.id = post_states.post_id
+  INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE
+  post_states.user_id = $1
+  AND post_states.starred_at IS NOT NULL
+ORDER BY
+  post_states.starred_at DESC
+LIMIT
+  $2;
+
+-- name: GetSavedPosts :many
+-- the read-later queue, oldest first
+SELECT
+  posts.id,
+  posts.created_at,
+  posts.updated_at,
+  posts.title,
+  posts.url,
+  posts.description,
+  posts.published_at,
+  posts.feed_id,
+  posts.guid,
+  posts.content_hash,
+  posts.source_updated_at,
+  feeds.name AS feed_name,
+  post_states.read,
+  post_states.saved_at
+FROM
+  post_states
+  INNER JOIN posts ON posts.id = post_states.post_id
+  INNER JOIN feeds ON feeds.id = posts.feed_id
+WHERE
+  post_states.user_id = $1
+  AND post_states.saved_at IS NOT NULL
+ORDER BY
+  post_states.saved_at
+LIMIT
+  $2;
@@ -8,21 +8,269 @@ INSERT INTO
     url,
     description,
     published_at,
-    feed_id
+    feed_id,
+    guid,
+    content_hash,
+    source_updated_at
   )
 VALUES
-  ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+  ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id;
 
--- name: GetPostsByUser :many
+-- name: GetPostByFeedAndGuid :one
+-- what savePost needs to tell whether an item changed
 SELECT
-  posts.*,
-  feeds.*
+  id,
+  title,
+  description,
+  content_hash,
+  source_updated_at
 FROM
   posts
+WHERE
+  feed_id = $1
+  AND guid = $2;
+
+-- name: UpdatePostContent :one
+-- the previous version of the post is kept in post_revisions
+WITH
+  revision AS (
+    INSERT INTO
+      post_revisions (
+        id,
+        created_at,
+        post_id,
+        title,
+        url,
+        description,
+        content_hash
+      )
+    SELECT
+      sqlc.arg(revision_id)::uuid,
+      Now(),
+      posts.id,
+      posts.title,
+      posts.url,
+      posts.description,
+      posts.content_hash
+    FROM
+      posts
+    WHERE
+      posts.id = sqlc.arg(post_id)
+  )
+UPDATE posts
+SET
+  updated_at = Now(),
+  title = sqlc.arg(title),
+  url = sqlc.arg(url),
+  description = sqlc.arg(description),
+  content_hash = sqlc.arg(content_hash),
+  source_updated_at = sqlc.arg(source_updated_at)
+WHERE
+  posts.id = sqlc.arg(post_id) RETURNING posts.id;
+
+-- name: GetPostRevisions :many
+SELECT
+  *
+FROM
+  post_revisions
+WHERE
+  post_id = $1
+ORDER BY
+  created_at DESC;
+
+-- name: BrowsePostsByPublished :many
+-- posts of the followed feeds, newest first. the cursor is the
+-- (published_at, id) of the last post of the previous page
+SELECT
+  posts.id,
+  posts.created_at,
+  posts.updated_at,
+  posts.title,
+  posts.url,
+  posts.description,
+  posts.published_at,
+  posts.feed_id,
+  posts.guid,
+  posts.content_hash,
+  posts.source_updated_at,
+  feeds.name AS feed_name,
+  COALESCE(post_states.read, FALSE) AS read
+FROM
+  posts
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+  INNER JOIN feeds ON feeds.id = posts.feed_id
+  LEFT JOIN post_states ON post_states.post_id = posts.id
+  AND post_states.user_id = feed_follows.user_id
+WHERE
+  feed_follows.user_id = sqlc.arg(user_id)
+  AND (
+    NOT sqlc.arg(unread_only)::boolean
+    OR post_states.read IS NOT TRUE
+  )
+  AND (
+    sqlc.narg(feed_id)::uuid IS NULL
+    OR posts.feed_id = sqlc.narg(feed_id)
+  )
+  AND (
+    sqlc.narg(category)::text IS NULL
+    OR feed_follows.category = sqlc.narg(category)
+    OR starts_with(feed_follows.category, sqlc.narg(category) || '/')
+  )
+  AND (
+    sqlc.narg(since)::timestamp IS NULL
+    OR posts.published_at >= sqlc.narg(since)
+  )
+  AND (
+    sqlc.narg(until)::timestamp IS NULL
+    OR posts.published_at < sqlc.narg(until)
+  )
+  AND (
+    sqlc.narg(cursor_time)::timestamp IS NULL
+    OR (posts.published_at, posts.id) < (
+      sqlc.narg(cursor_time)::timestamp,
+      sqlc.narg(cursor_id)::uuid
+    )
+  )
+ORDER BY
+  posts.published_at DESC,
+  posts.id DESC
+LIMIT
+  sqlc.arg(post_limit)
+OFFSET
+  sqlc.arg(post_offset);
+
+-- name: BrowsePostsByFetched :many
+-- posts of the followed feeds, newest fetched first. the cursor is the
+-- (created_at, id) of the last post of the previous page
+SELECT
+  posts.id,
+  posts.created_at,
+  posts.updated_at,
+  posts.title,
+  posts.url,
+  posts.description,
+  posts.published_at,
+  posts.feed_id,
+  posts.guid,
+  posts.content_hash,
+  posts.source_updated_at,
+  feeds.name AS feed_name,
+  COALESCE(post_states.read, FALSE) AS read
+FROM
+  posts
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
+  INNER JOIN feeds ON feeds.id = posts.feed_id
+  LEFT JOIN post_states ON post_states.post_id = posts.id
+  AND post_states.user_id = feed_follows.user_id
+WHERE
+  feed_follows.user_id = sqlc.arg(user_id)
+  AND (
+    NOT sqlc.arg(unread_only)::boolean
+    OR post_states.read IS NOT TRUE
+  )
+  AND (
+    sqlc.narg(feed_id)::uuid IS NULL
+    OR posts.feed_id = sqlc.narg(feed_id)
+  )
+  AND (
+    sqlc.narg(category)::text IS NULL
+    OR feed_follows.category = sqlc.narg(category)
+    OR starts_with(feed_follows.category, sqlc.narg(category) || '/')
+  )
+  AND (
+    sqlc.narg(since)::timestamp IS NULL
+    OR posts.created_at >= sqlc.narg(since)
+  )
+  AND (
+    sqlc.narg(until)::timestamp IS NULL
+    OR posts.created_at < sqlc.narg(until)
+  )
+  AND (
+    sqlc.narg(cursor_time)::timestamp IS NULL
+    OR (posts.created_at, posts.id) < (
+      sqlc.narg(cursor_time)::timestamp,
+      sqlc.narg(cursor_id)::uuid
+    )
+  )
+ORDER BY
+  posts.created_at DESC,
+  posts.id DESC
+LIMIT
+  sqlc.arg(post_limit)
+OFFSET
+  sqlc.arg(post_offset);
+
+-- name: PrunePosts :execrows
+-- posts starred or saved by anyone are kept whatever their age, the guids of
+-- the others are kept so the next fetch doesn't store them again
+WITH
+  pruned AS (
+    DELETE FROM posts
+    WHERE
+      posts.published_at < sqlc.arg(published_before)
+      AND NOT EXISTS (
+        SELECT
+          1
+        FROM
+          post_states
+        WHERE
+          post_states.post_id = posts.id
+          AND (
+            post_states.starred_at IS NOT NULL
+            OR post_states.saved_at IS NOT NULL
+          )
+      ) RETURNING posts.feed_id,
+      posts.guid
+  )
+INSERT INTO
+  pruned_posts (feed_id, guid, pruned_at)
+SELECT
+  pruned.feed_id,
+  pruned.guid,
+  Now()
+FROM
+  pruned
+ON CONFLICT (feed_id, guid) DO NOTHING;
+
+-- name: IsPostPruned :one
+SELECT
+  EXISTS (
+    SELECT
+      1
+    FROM
+      pruned_posts
+    WHERE
+      pruned_posts.feed_id = $1
+      AND pruned_posts.guid = $2
+  );
+
+-- name: SearchPosts :many
+-- query is a to_tsquery expression, searched in the followed feeds only
+SELECT
+  posts.id,
+  posts.title,
+  posts.url,
+  posts.published_at,
+  feeds.name AS feed_name,
+  ts_rank(
+    posts.search_vector,
+    to_tsquery('english', sqlc.arg(query)::text)
+  ) AS rank,
+  ts_headline(
+    'english',
+    regexp_replace(posts.description, '<[^>]*>', ' ', 'g'),
+    to_tsquery('english', sqlc.arg(query)::text),
+    'StartSel=**, StopSel=**, MaxFragments=2, MinWords=5, MaxWords=20'
+  ) AS snippet
+FROM
+  posts
+  INNER JOIN feed_follows ON feed_follows.feed_id = posts.feed_id
   INNER JOIN feeds ON feeds.id = posts.feed_id
 WHERE
-  feeds.user_id = $1
+  feed_follows.user_id = sqlc.arg(user_id)
+  AND posts.search_vector @@ to_tsquery('english', sqlc.arg(query)::text)
 ORDER BY
+  rank DESC,
   posts.published_at DESC
 LIMIT
-  $2;
+  sqlc.arg(post_limit);
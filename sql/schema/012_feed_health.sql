@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN consecutive_failures INT NOT NULL DEFAULT 0,
+ADD COLUMN last_success_at TIMESTAMP,
+ADD COLUMN last_status_code INT,
+ADD COLUMN paused_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN paused_at,
+DROP COLUMN last_status_code,
+DROP COLUMN last_success_at,
+DROP COLUMN consecutive_failures;
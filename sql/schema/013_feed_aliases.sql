@@ -0,0 +1,20 @@
+-- +goose Up
+-- urls a feed was known by before a permanent redirect
+CREATE TABLE feed_aliases (
+  url TEXT PRIMARY KEY,
+  feed_id UUID NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+  created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE feed_history (
+  id UUID PRIMARY KEY,
+  created_at TIMESTAMP NOT NULL,
+  feed_id UUID NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+  event TEXT NOT NULL,
+  detail TEXT NOT NULL
+);
+
+-- +goose Down
+DROP TABLE feed_history;
+
+DROP TABLE feed_aliases;
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE feeds
+ADD COLUMN site_url TEXT,
+ADD COLUMN description TEXT,
+ADD COLUMN icon_url TEXT,
+ADD COLUMN language TEXT;
+
+-- +goose Down
+ALTER TABLE feeds
+DROP COLUMN language,
+DROP COLUMN icon_url,
+DROP COLUMN description,
+DROP COLUMN site_url;
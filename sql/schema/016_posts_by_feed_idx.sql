@@ -0,0 +1,6 @@
+-- +goose Up
+-- browse reads the newest posts of each followed feed
+CREATE INDEX posts_feed_id_published_at_idx ON posts (feed_id, published_at DESC);
+
+-- +goose Down
+DROP INDEX posts_feed_id_published_at_idx;
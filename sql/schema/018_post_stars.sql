@@ -0,0 +1,9 @@
+-- +goose Up
+ALTER TABLE post_states
+ADD COLUMN starred_at TIMESTAMP,
+ADD COLUMN saved_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE post_states
+DROP COLUMN saved_at,
+DROP COLUMN starred_at;
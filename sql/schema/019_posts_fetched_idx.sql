@@ -0,0 +1,6 @@
+-- +goose Up
+-- browse --sort fetched reads the posts of each followed feed by fetch date
+CREATE INDEX posts_feed_id_created_at_idx ON posts (feed_id, created_at DESC);
+
+-- +goose Down
+DROP INDEX posts_feed_id_created_at_idx;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- guids of the posts deleted by prune, so feeds still listing them don't
+-- bring them back as new posts
+CREATE TABLE pruned_posts (
+  feed_id UUID NOT NULL REFERENCES feeds (id) ON DELETE CASCADE,
+  guid TEXT NOT NULL,
+  pruned_at TIMESTAMP NOT NULL,
+  PRIMARY KEY (feed_id, guid)
+);
+
+-- +goose Down
+DROP TABLE pruned_posts;